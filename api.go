@@ -5,16 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jtumidanski/api2go/jsonapi"
-	"github.com/jtumidanski/api2go/routing"
 )
 
 const (
@@ -40,7 +40,7 @@ func (i information) GetPrefix() string {
 }
 
 type paginationQueryParams struct {
-	number, size, offset, limit string
+	number, size, offset, limit, after, before string
 }
 
 func newPaginationQueryParams(r *http.Request) paginationQueryParams {
@@ -51,11 +51,17 @@ func newPaginationQueryParams(r *http.Request) paginationQueryParams {
 	result.size = queryParams.Get("page[size]")
 	result.offset = queryParams.Get("page[offset]")
 	result.limit = queryParams.Get("page[limit]")
+	result.after = queryParams.Get("page[after]")
+	result.before = queryParams.Get("page[before]")
 
 	return result
 }
 
 func (p paginationQueryParams) isValid() bool {
+	if p.after != "" || p.before != "" {
+		return false
+	}
+
 	if p.number == "" && p.size == "" && p.offset == "" && p.limit == "" {
 		return false
 	}
@@ -164,33 +170,159 @@ func (p paginationQueryParams) getLinks(r *http.Request, count uint, info inform
 	return
 }
 
+// bounds returns the range of the page within count elements
+func (p paginationQueryParams) bounds(count int) (int, int, error) {
+	var start, size uint64
+	if p.number != "" {
+		number, err := strconv.ParseUint(p.number, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		if size, err = strconv.ParseUint(p.size, 10, 64); err != nil {
+			return 0, 0, err
+		}
+		if number > 0 {
+			start = (number - 1) * size
+		}
+	} else {
+		var err error
+		if start, err = strconv.ParseUint(p.offset, 10, 64); err != nil {
+			return 0, 0, err
+		}
+		if size, err = strconv.ParseUint(p.limit, 10, 64); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	end := start + size
+	if start > uint64(count) {
+		start = uint64(count)
+	}
+	if end > uint64(count) {
+		end = uint64(count)
+	}
+
+	return int(start), int(end), nil
+}
+
+// isCursor returns true if the request asks for cursor based pagination, which
+// is a page[after] or page[before] cursor, or only a page[size] for the first page.
+func (p paginationQueryParams) isCursor() bool {
+	if p.number != "" || p.offset != "" || p.limit != "" {
+		return false
+	}
+
+	if p.after != "" && p.before != "" {
+		return false
+	}
+
+	return p.after != "" || p.before != "" || p.size != ""
+}
+
+func (p paginationQueryParams) getCursorLinks(r *http.Request, page CursorPage, info information) jsonapi.Links {
+	result := make(jsonapi.Links)
+
+	prefix := ""
+	baseURL := strings.Trim(info.GetBaseURL(), "/")
+	if baseURL != "" {
+		prefix = baseURL
+	}
+	requestURL := fmt.Sprintf("%s%s", prefix, r.URL.Path)
+
+	// cursors are opaque, so they must stay escaped, only the brackets are kept readable
+	link := func(params url.Values) jsonapi.Link {
+		query := strings.NewReplacer("%5B", "[", "%5D", "]").Replace(params.Encode())
+		if query == "" {
+			return jsonapi.Link{Href: requestURL}
+		}
+		return jsonapi.Link{Href: fmt.Sprintf("%s?%s", requestURL, query)}
+	}
+
+	if p.after != "" || p.before != "" {
+		params := r.URL.Query()
+		params.Del("page[after]")
+		params.Del("page[before]")
+		result["first"] = link(params)
+	}
+
+	if page.Prev != "" {
+		params := r.URL.Query()
+		params.Del("page[after]")
+		params.Set("page[before]", page.Prev)
+		result["prev"] = link(params)
+	}
+
+	if page.Next != "" {
+		params := r.URL.Query()
+		params.Del("page[before]")
+		params.Set("page[after]", page.Next)
+		result["next"] = link(params)
+	}
+
+	return result
+}
+
+func (p paginationQueryParams) getCursorMeta(page CursorPage) map[string]interface{} {
+	meta := map[string]interface{}{
+		"hasNext": page.Next != "",
+		"hasPrev": page.Prev != "",
+	}
+
+	if page.Next != "" {
+		meta["nextCursor"] = page.Next
+	}
+
+	if page.Prev != "" {
+		meta["prevCursor"] = page.Prev
+	}
+
+	if size, err := strconv.ParseUint(p.size, 10, 64); err == nil {
+		meta["size"] = size
+	}
+
+	return meta
+}
+
 type notAllowedHandler struct {
 	API *API
 }
 
 func (n notAllowedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	err := NewHTTPError(nil, "Method Not Allowed", http.StatusMethodNotAllowed)
-	w.WriteHeader(http.StatusMethodNotAllowed)
-
-	contentType := defaultContentTypHeader
-	if n.API != nil {
-		contentType = n.API.ContentType
+	if n.API == nil {
+		writeResult(w, []byte(marshalHTTPError(err)), http.StatusMethodNotAllowed, defaultContentTypHeader)
+		return
 	}
 
-	handleError(err, w, r, contentType)
+	n.API.handleError(w, r, ErrorInfo{Err: err})
 }
 
 type resource struct {
 	resourceType reflect.Type
+	prototype    jsonapi.MarshalIdentifier
 	source       interface{}
 	name         string
 	api          *API
+	middlewares  map[Action][]HandlerFunc
 }
 
-// middlewareChain executes the middleeware chain setup
-func (api *API) middlewareChain(c APIContexter, w http.ResponseWriter, r *http.Request) {
+// middlewareChain executes the middleeware chain setup. The chain stops as
+// soon as a middleware writes a response, it returns false in that case.
+func (api *API) middlewareChain(c APIContexter, w *statusWriter, r *http.Request) bool {
 	for _, middleware := range api.middlewares {
 		middleware(c, w, r)
+		if w.written() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// responseHooks calls all registered response hooks
+func (api *API) responseHooks(c APIContexter, r *http.Request, info ResponseInfo) {
+	for _, hook := range api.hooks {
+		hook(c, r, info)
 	}
 }
 
@@ -199,118 +331,126 @@ func (api *API) allocateDefaultContext() APIContexter {
 	return &APIContext{}
 }
 
+// requestInfo returns the server information for the given request
+func (api *API) requestInfo(r *http.Request) *information {
+	var info *information
+	if resolver, ok := api.info.resolver.(RequestAwareURLResolver); ok {
+		resolver.SetRequest(*r)
+		info = &information{prefix: api.info.prefix, resolver: resolver}
+	} else {
+		info = &api.info
+	}
+
+	return info
+}
+
+// routeHandlerFunc is the signature of all generated api2go routes
+type routeHandlerFunc func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error
+
+// handle registers a route that runs the middleware chain and the content
+// negotiation before calling the handler and that writes all returned errors.
+// The handler is skipped if a middleware already wrote a response.
+func (api *API) handle(method, route string, handler routeHandlerFunc) {
+	api.handleRoute(nil, "", method, route, true, handler)
+}
+
+// handlePlain registers a route like handle that does not serve JSON:API
+// documents and therefore skips the content negotiation.
+func (api *API) handlePlain(method, route string, handler routeHandlerFunc) {
+	api.handleRoute(nil, "", method, route, false, handler)
+}
+
+// handle registers a route of the resource, which runs the middlewares of the
+// resource and the action after the middlewares of the api.
+func (res *resource) handle(action Action, method, route string, handler routeHandlerFunc) {
+	res.api.handleRoute(res, action, method, route, true, handler)
+}
+
+// handleRoute registers a route like handle, res is nil for routes that do
+// not belong to a resource.
+func (api *API) handleRoute(res *resource, action Action, method, route string, negotiate bool, handler routeHandlerFunc) {
+	api.router.Handle(method, route, func(rw http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
+		start := time.Now()
+		w := &statusWriter{ResponseWriter: rw}
+		info := api.requestInfo(r)
+		c := api.contextPool.Get().(APIContexter)
+		c.Reset()
+		if setter, ok := c.(RequestContextSetter); ok {
+			setter.SetRequestContext(r.Context())
+		}
+
+		for key, val := range context {
+			c.Set(key, val)
+		}
+
+		var err error
+		if api.middlewareChain(c, w, r) && (res == nil || res.middlewareChain(action, c, w, r)) {
+			if negotiate {
+				r, err = api.negotiate(r)
+			}
+			r = withIncludePaths(r)
+			if err == nil {
+				err = handler(c, w, r, params, *info)
+			}
+			if err != nil {
+				errorInfo := ErrorInfo{Action: action, Err: err}
+				if res != nil {
+					errorInfo.Resource = res.name
+				}
+				api.handleError(w, r, errorInfo)
+			}
+		}
+
+		api.responseHooks(c, r, ResponseInfo{Status: w.status, Duration: time.Since(start), Err: err})
+		api.contextPool.Put(c)
+	})
+}
+
 func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interface{}) *resource {
 	resourceType := reflect.TypeOf(prototype)
 	if resourceType.Kind() != reflect.Struct && resourceType.Kind() != reflect.Ptr {
 		panic("pass an empty resource struct or a struct pointer to AddResource!")
 	}
 
-	var ptrPrototype interface{}
-	var name string
-
-	if resourceType.Kind() == reflect.Struct {
-		ptrPrototype = reflect.New(resourceType).Interface()
-		name = resourceType.Name()
-	} else {
-		ptrPrototype = reflect.ValueOf(prototype).Interface()
-		name = resourceType.Elem().Name()
+	if err := checkValidationRules(resourceType); err != nil {
+		panic(err.Error())
 	}
 
-	// check if EntityNamer interface is implemented and use that as name
-	entityName, ok := prototype.(jsonapi.EntityNamer)
-	if ok {
-		name = entityName.GetName()
-	} else {
-		name = jsonapi.Jsonify(jsonapi.Pluralize(name))
-	}
+	name := jsonapi.EntityName(prototype)
 
-	res := resource{
+	res := &resource{
 		resourceType: resourceType,
+		prototype:    prototype,
 		name:         name,
 		source:       source,
 		api:          api,
 	}
 
-	requestInfo := func(r *http.Request, api *API) *information {
-		var info *information
-		if resolver, ok := api.info.resolver.(RequestAwareURLResolver); ok {
-			resolver.SetRequest(*r)
-			info = &information{prefix: api.info.prefix, resolver: resolver}
-		} else {
-			info = &api.info
-		}
-
-		return info
-	}
-
 	prefix := strings.Trim(api.info.prefix, "/")
 	baseURL := "/" + name
 	if prefix != "" {
 		baseURL = "/" + prefix + baseURL
 	}
 
-	api.router.Handle("OPTIONS", baseURL, func(w http.ResponseWriter, r *http.Request, _ map[string]string, context map[string]interface{}) {
-		c := api.contextPool.Get().(APIContexter)
-		c.Reset()
-
-		for key, val := range context {
-			c.Set(key, val)
-		}
-
-		api.middlewareChain(c, w, r)
+	res.handle(ActionOptions, "OPTIONS", baseURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string, _ information) error {
 		w.Header().Set("Allow", strings.Join(getAllowedMethods(source, true), ","))
 		w.WriteHeader(http.StatusNoContent)
-		api.contextPool.Put(c)
+		return nil
 	})
 
-	api.router.Handle("GET", baseURL, func(w http.ResponseWriter, r *http.Request, _ map[string]string, context map[string]interface{}) {
-		info := requestInfo(r, api)
-		c := api.contextPool.Get().(APIContexter)
-		c.Reset()
-
-		for key, val := range context {
-			c.Set(key, val)
-		}
-
-		api.middlewareChain(c, w, r)
-
-		err := res.handleIndex(c, w, r, *info)
-		api.contextPool.Put(c)
-		if err != nil {
-			handleError(err, w, r, api.ContentType)
-		}
+	res.handle(ActionFindAll, "GET", baseURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string, info information) error {
+		return res.handleIndex(c, w, r, info)
 	})
 
-	if _, ok := source.(ResourceGetter); ok {
-		api.router.Handle("OPTIONS", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, _ map[string]string, context map[string]interface{}) {
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
-
-			for key, val := range context {
-				c.Set(key, val)
-			}
-
-			api.middlewareChain(c, w, r)
+	if _, ok := sourceAs[ResourceGetter](source); ok {
+		res.handle(ActionOptions, "OPTIONS", baseURL+"/:id", func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string, _ information) error {
 			w.Header().Set("Allow", strings.Join(getAllowedMethods(source, false), ","))
 			w.WriteHeader(http.StatusNoContent)
-			api.contextPool.Put(c)
+			return nil
 		})
 
-		api.router.Handle("GET", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
-			info := requestInfo(r, api)
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
-
-			for key, val := range context {
-				c.Set(key, val)
-			}
-
-			api.middlewareChain(c, w, r)
-			err := res.handleRead(c, w, r, params, *info)
-			api.contextPool.Put(c)
-			if err != nil {
-				handleError(err, w, r, api.ContentType)
-			}
+		res.handle(ActionFindOne, "GET", baseURL+"/:id", func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+			return res.handleRead(c, w, r, params, info)
 		})
 	}
 
@@ -319,180 +459,89 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 	if ok {
 		relations := casted.GetReferences()
 		for _, relation := range relations {
-			api.router.Handle("GET", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-				return func(w http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
-					info := requestInfo(r, api)
-					c := api.contextPool.Get().(APIContexter)
-					c.Reset()
-
-					for key, val := range context {
-						c.Set(key, val)
-					}
-
-					api.middlewareChain(c, w, r)
-					err := res.handleReadRelation(c, w, r, params, *info, relation)
-					api.contextPool.Put(c)
-					if err != nil {
-						handleError(err, w, r, api.ContentType)
-					}
-				}
-			}(relation))
-
-			api.router.Handle("GET", baseURL+"/:id/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-				return func(w http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
-					info := requestInfo(r, api)
-					c := api.contextPool.Get().(APIContexter)
-					c.Reset()
-
-					for key, val := range context {
-						c.Set(key, val)
-					}
-
-					api.middlewareChain(c, w, r)
-					err := res.handleLinked(c, api, w, r, params, relation, *info)
-					api.contextPool.Put(c)
-					if err != nil {
-						handleError(err, w, r, api.ContentType)
-					}
-				}
-			}(relation))
-
-			api.router.Handle("PATCH", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-				return func(w http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
-					c := api.contextPool.Get().(APIContexter)
-					c.Reset()
+			res.handle(ActionReadRelationship, "GET", baseURL+"/:id/relationships/"+relation.Name, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+				return res.handleReadRelation(c, w, r, params, info, relation)
+			})
 
-					for key, val := range context {
-						c.Set(key, val)
-					}
+			res.handle(ActionReadRelated, "GET", baseURL+"/:id/"+relation.Name, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+				return res.handleLinked(c, api, w, r, params, relation, info)
+			})
 
-					api.middlewareChain(c, w, r)
-					err := res.handleReplaceRelation(c, w, r, params, relation)
-					api.contextPool.Put(c)
-					if err != nil {
-						handleError(err, w, r, api.ContentType)
-					}
-				}
-			}(relation))
+			res.handle(ActionReplaceRelationship, "PATCH", baseURL+"/:id/relationships/"+relation.Name, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+				return res.handleRelationshipUpdate(c, w, r, params, info, relation, ActionReplaceRelationship)
+			})
 
-			if _, ok := ptrPrototype.(jsonapi.EditToManyRelations); ok && relation.Name == jsonapi.Pluralize(relation.Name) {
+			if res.editsToManyRelation(relation) {
 				// generate additional routes to manipulate to-many relationships
-				api.router.Handle("POST", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-					return func(w http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
-						c := api.contextPool.Get().(APIContexter)
-						c.Reset()
-
-						for key, val := range context {
-							c.Set(key, val)
-						}
-
-						api.middlewareChain(c, w, r)
-						err := res.handleAddToManyRelation(c, w, r, params, relation)
-						api.contextPool.Put(c)
-						if err != nil {
-							handleError(err, w, r, api.ContentType)
-						}
-					}
-				}(relation))
-
-				api.router.Handle("DELETE", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-					return func(w http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
-						c := api.contextPool.Get().(APIContexter)
-						c.Reset()
-
-						for key, val := range context {
-							c.Set(key, val)
-						}
-
-						api.middlewareChain(c, w, r)
-						err := res.handleDeleteToManyRelation(c, w, r, params, relation)
-						api.contextPool.Put(c)
-						if err != nil {
-							handleError(err, w, r, api.ContentType)
-						}
-					}
-				}(relation))
+				res.handle(ActionAddToManyRelationship, "POST", baseURL+"/:id/relationships/"+relation.Name, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+					return res.handleRelationshipUpdate(c, w, r, params, info, relation, ActionAddToManyRelationship)
+				})
+
+				res.handle(ActionDeleteToManyRelationship, "DELETE", baseURL+"/:id/relationships/"+relation.Name, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+					return res.handleRelationshipUpdate(c, w, r, params, info, relation, ActionDeleteToManyRelationship)
+				})
 			}
 		}
 	}
 
-	if _, ok := source.(ResourceCreator); ok {
-		api.router.Handle("POST", baseURL, func(w http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
-			info := requestInfo(r, api)
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
-
-			for key, val := range context {
-				c.Set(key, val)
-			}
-
-			api.middlewareChain(c, w, r)
-			err := res.handleCreate(c, w, r, info.prefix, *info)
-			api.contextPool.Put(c)
-			if err != nil {
-				handleError(err, w, r, api.ContentType)
-			}
+	_, isCreator := sourceAs[ResourceCreator](source)
+	if _, isBulkCreator := sourceAs[BulkCreator](source); isCreator || isBulkCreator {
+		res.handle(ActionCreate, "POST", baseURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string, info information) error {
+			return res.handleCreate(c, w, r, info.prefix, info)
 		})
 	}
 
-	if _, ok := source.(ResourceDeleter); ok {
-		api.router.Handle("DELETE", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
-
-			for key, val := range context {
-				c.Set(key, val)
-			}
-
-			api.middlewareChain(c, w, r)
-			err := res.handleDelete(c, w, r, params)
-			api.contextPool.Put(c)
-			if err != nil {
-				handleError(err, w, r, api.ContentType)
-			}
+	if _, ok := sourceAs[BulkUpdater](source); ok {
+		res.handle(ActionUpdate, "PATCH", baseURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string, info information) error {
+			return res.handleBulkUpdate(c, w, r, info)
 		})
 	}
 
-	if _, ok := source.(ResourceUpdater); ok {
-		api.router.Handle("PATCH", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string, context map[string]interface{}) {
-			info := requestInfo(r, api)
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
+	if _, ok := sourceAs[BulkDeleter](source); ok {
+		res.handle(ActionDelete, "DELETE", baseURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string, _ information) error {
+			return res.handleBulkDelete(c, w, r)
+		})
+	}
 
-			for key, val := range context {
-				c.Set(key, val)
-			}
+	if _, ok := sourceAs[ResourceDeleter](source); ok {
+		res.handle(ActionDelete, "DELETE", baseURL+"/:id", func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+			return res.handleDelete(c, w, r, params, info)
+		})
+	}
 
-			api.middlewareChain(c, w, r)
-			err := res.handleUpdate(c, w, r, params, *info)
-			api.contextPool.Put(c)
-			if err != nil {
-				handleError(err, w, r, api.ContentType)
-			}
+	if _, ok := sourceAs[ResourceUpdater](source); ok {
+		res.handle(ActionUpdate, "PATCH", baseURL+"/:id", func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+			return res.handleUpdate(c, w, r, params, info)
 		})
 	}
 
 	api.resources = append(api.resources, res)
 
-	return &res
+	return res
 }
 
 func getAllowedMethods(source interface{}, collection bool) []string {
 	result := []string{http.MethodOptions}
 
-	if _, ok := source.(ResourceGetter); ok {
+	if _, ok := sourceAs[ResourceGetter](source); ok {
 		result = append(result, http.MethodGet)
 	}
 
-	if _, ok := source.(ResourceUpdater); ok {
+	if _, ok := sourceAs[ResourceUpdater](source); ok {
+		result = append(result, http.MethodPatch)
+	} else if _, ok := sourceAs[BulkUpdater](source); ok && collection {
 		result = append(result, http.MethodPatch)
 	}
 
-	if _, ok := source.(ResourceDeleter); ok && !collection {
+	if _, ok := sourceAs[ResourceDeleter](source); ok && !collection {
+		result = append(result, http.MethodDelete)
+	} else if _, ok := sourceAs[BulkDeleter](source); ok && collection {
 		result = append(result, http.MethodDelete)
 	}
 
-	if _, ok := source.(ResourceCreator); ok && collection {
+	if _, ok := sourceAs[ResourceCreator](source); ok && collection {
+		result = append(result, http.MethodPost)
+	} else if _, ok := sourceAs[BulkCreator](source); ok && collection {
 		result = append(result, http.MethodPost)
 	}
 
@@ -512,32 +561,70 @@ func buildRequest(c APIContexter, r *http.Request) Request {
 	}
 	req.Pagination = pagination
 	req.QueryParams = params
+	req.Include, _ = includePaths(r)
+	req.Sort = parseSortFields(r)
+	req.Filters, _, _ = parseFilters(r)
+	query := r.URL.Query()
+	req.Fields = jsonapi.ParseQueryFields(&query)
+	negotiated := getNegotiation(r)
+	req.Extensions = negotiated.extensions
+	req.Profiles = negotiated.profiles
 	req.Header = r.Header
 	req.Context = c
 	return req
 }
 
-func (res *resource) marshalResponse(resp interface{}, w http.ResponseWriter, status int, r *http.Request) error {
-	query := r.URL.Query()
-	queryParams := jsonapi.ParseQueryFields(&query)
-
-	filtered, errs := jsonapi.FilterSparseFields(resp, queryParams)
-	if errs != nil {
-		httpError := NewHTTPError(nil, "Some requested fields were invalid", http.StatusBadRequest)
-		httpError.Errors = errs
-		return httpError
-	}
-
-	result, err := json.Marshal(filtered)
+// marshalResponse writes resp with an ETag, which is computed from the
+// response if etag is empty. GET requests with a matching If-None-Match
+// header are answered with 304 Not Modified.
+func (res *resource) marshalResponse(resp interface{}, etag string, w http.ResponseWriter, status int, r *http.Request) error {
+	result, err := json.Marshal(resp)
 	if err != nil {
 		return err
 	}
-	writeResult(w, result, status, res.api.ContentType)
+
+	if (status == http.StatusOK || status == http.StatusCreated) && r.Method != http.MethodDelete {
+		if etag == "" {
+			etag = computeETag(result)
+		}
+		w.Header().Set("ETag", etag)
+
+		if r.Method == http.MethodGet && matchETag(r.Header.Get("If-None-Match"), etag, true) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	writeResult(w, result, status, res.api.contentType(r))
 	return nil
 }
 
 func (res *resource) handleIndex(c APIContexter, w http.ResponseWriter, r *http.Request, info information) error {
-	if source, ok := res.source.(PaginatedFindAll); ok {
+	if err := res.validateIncludes(r); err != nil {
+		return err
+	}
+
+	if err := validateSort(res.source, r); err != nil {
+		return err
+	}
+
+	if err := validateFilters(res.source, r); err != nil {
+		return err
+	}
+
+	cursorSource, isCursorSource := sourceAs[CursorPaginatedFindAll](res.source)
+	if isCursorSource {
+		pagination := newPaginationQueryParams(r)
+		if pagination.after != "" && pagination.before != "" {
+			return newCursorConflictError()
+		}
+
+		if pagination.isCursor() {
+			return res.respondWithCursorPage(c, cursorSource, pagination, info, w, r)
+		}
+	}
+
+	if source, ok := sourceAs[PaginatedFindAll](res.source); ok {
 		pagination := newPaginationQueryParams(r)
 
 		if pagination.isValid() {
@@ -551,11 +638,15 @@ func (res *resource) handleIndex(c APIContexter, w http.ResponseWriter, r *http.
 				return err
 			}
 
-			return res.respondWithPagination(response, info, http.StatusOK, paginationLinks, w, r)
+			return res.respondWithPagination(c, response, info, http.StatusOK, paginationLinks, w, r)
 		}
 	}
 
-	source, ok := res.source.(FindAll)
+	source, ok := sourceAs[FindAll](res.source)
+	if !ok && isCursorSource {
+		// serve the first page of sources that only paginate by cursor
+		return res.respondWithCursorPage(c, cursorSource, newPaginationQueryParams(r), info, w, r)
+	}
 	if !ok {
 		return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
 	}
@@ -565,16 +656,44 @@ func (res *resource) handleIndex(c APIContexter, w http.ResponseWriter, r *http.
 		return err
 	}
 
-	return res.respondWith(response, info, http.StatusOK, w, r)
+	return res.respondWith(c, response, info, http.StatusOK, w, r)
+}
+
+// respondWithCursorPage loads the requested page with CursorPaginatedFindAll
+func (res *resource) respondWithCursorPage(c APIContexter, source CursorPaginatedFindAll, pagination paginationQueryParams, info information, w http.ResponseWriter, r *http.Request) error {
+	page, response, err := source.CursorPaginatedFindAll(buildRequest(c, r))
+	if err != nil {
+		return err
+	}
+
+	return res.respondWithCursorPagination(c, response, info, pagination, page, w, r)
+}
+
+// newCursorConflictError is returned for requests with page[after] and
+// page[before]
+func newCursorConflictError() HTTPError {
+	httpError := NewHTTPError(nil, "Invalid pagination query parameters", http.StatusBadRequest)
+	httpError.Errors = []jsonapi.Error{{
+		Status: strconv.Itoa(http.StatusBadRequest),
+		Title:  "Invalid pagination query parameters",
+		Detail: "page[after] and page[before] can not be combined",
+		Source: &jsonapi.ErrorSource{Parameter: "page[before]"},
+	}}
+
+	return httpError
 }
 
 func (res *resource) handleRead(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
-	source, ok := res.source.(ResourceGetter)
+	source, ok := sourceAs[ResourceGetter](res.source)
 
 	if !ok {
 		return fmt.Errorf("Resource %s does not implement the ResourceGetter interface", res.name)
 	}
 
+	if err := res.validateIncludes(r); err != nil {
+		return err
+	}
+
 	id := params["id"]
 
 	response, err := source.FindOne(id, buildRequest(c, r))
@@ -583,11 +702,11 @@ func (res *resource) handleRead(c APIContexter, w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	return res.respondWith(response, info, http.StatusOK, w, r)
+	return res.respondWith(c, response, info, http.StatusOK, w, r)
 }
 
 func (res *resource) handleReadRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information, relation jsonapi.Reference) error {
-	source, ok := res.source.(ResourceGetter)
+	source, ok := sourceAs[ResourceGetter](res.source)
 
 	if !ok {
 		return fmt.Errorf("Resource %s does not implement the ResourceGetter interface", res.name)
@@ -600,34 +719,58 @@ func (res *resource) handleReadRelation(c APIContexter, w http.ResponseWriter, r
 		return err
 	}
 
-	document, err := jsonapi.MarshalToStruct(obj.Result(), info)
-	if err != nil {
-		return err
-	}
-
-	rel, ok := document.Data.DataObject.Relationships[relation.Name]
-	if !ok {
-		return NewHTTPError(nil, fmt.Sprintf("There is no relation with the name %s", relation.Name), http.StatusNotFound)
-	}
-
-	meta := obj.Metadata()
-	if len(meta) > 0 {
-		rel.Meta = meta
-	}
-
-	return res.marshalResponse(rel, w, http.StatusOK, r)
+	return res.respondWithRelationship(obj, info, relation, w, r)
 }
 
-// try to find the referenced resource and call the findAll Method with referencing resource id as param
+// try to find the referenced resource and call its FindRelated method or, if it does not implement
+// RelatedResourceFinder, the findAll Method with referencing resource id as param
 func (res *resource) handleLinked(c APIContexter, api *API, w http.ResponseWriter, r *http.Request, params map[string]string, linked jsonapi.Reference, info information) error {
 	id := params["id"]
+	toMany := isToManyReference(linked)
+	if linked.IsPolymorphic() {
+		return res.handlePolymorphicLinked(c, api, w, r, id, linked, info)
+	}
+
 	for _, resource := range api.resources {
 		if resource.name == linked.Type {
+			if err := resource.validateIncludes(r); err != nil {
+				return err
+			}
+
+			if err := validateSort(resource.source, r); err != nil {
+				return err
+			}
+
+			if err := validateFilters(resource.source, r); err != nil {
+				return err
+			}
+
+			if finder, ok := sourceAs[RelatedResourceFinder](resource.source); ok {
+				obj, err := finder.FindRelated(res.name, id, linked.Name, buildRequest(c, r))
+				if err != nil {
+					return err
+				}
+
+				return resource.respondWithRelated(c, obj, linked, info, w, r)
+			}
+
 			request := buildRequest(c, r)
 			request.QueryParams[res.name+"ID"] = []string{id}
 			request.QueryParams[res.name+"Name"] = []string{linked.Name}
 
-			if source, ok := resource.source.(PaginatedFindAll); ok {
+			if source, ok := sourceAs[CursorPaginatedFindAll](resource.source); ok && toMany {
+				pagination := newPaginationQueryParams(r)
+				if pagination.isCursor() {
+					page, response, err := source.CursorPaginatedFindAll(request)
+					if err != nil {
+						return err
+					}
+
+					return resource.respondWithCursorPagination(c, response, info, pagination, page, w, r)
+				}
+			}
+
+			if source, ok := sourceAs[PaginatedFindAll](resource.source); ok && toMany {
 				// check for pagination, otherwise normal FindAll
 				pagination := newPaginationQueryParams(r)
 				if pagination.isValid() {
@@ -642,11 +785,11 @@ func (res *resource) handleLinked(c APIContexter, api *API, w http.ResponseWrite
 						return err
 					}
 
-					return res.respondWithPagination(response, info, http.StatusOK, paginationLinks, w, r)
+					return resource.respondWithPagination(c, response, info, http.StatusOK, paginationLinks, w, r)
 				}
 			}
 
-			source, ok := resource.source.(FindAll)
+			source, ok := sourceAs[FindAll](resource.source)
 			if !ok {
 				return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
 			}
@@ -655,7 +798,7 @@ func (res *resource) handleLinked(c APIContexter, api *API, w http.ResponseWrite
 			if err != nil {
 				return err
 			}
-			return res.respondWith(obj, info, http.StatusOK, w, r)
+			return resource.respondWithRelated(c, obj, linked, info, w, r)
 		}
 	}
 
@@ -666,45 +809,213 @@ func (res *resource) handleLinked(c APIContexter, api *API, w http.ResponseWrite
 	)
 }
 
-func (res *resource) handleCreate(c APIContexter, w http.ResponseWriter, r *http.Request, prefix string, info information) error {
-	source, ok := res.source.(ResourceCreator)
+// handlePolymorphicLinked writes the related resources of a polymorphic
+// relationship in the order of its linkage. To-many relationships are paginated
+// with page[number] and page[size] or page[offset] and page[limit] before the
+// resources of the page are loaded.
+func (res *resource) handlePolymorphicLinked(c APIContexter, api *API, w http.ResponseWriter, r *http.Request, id string, linked jsonapi.Reference, info information) error {
+	if err := api.validateIncludes(api.referencedResources(linked), r); err != nil {
+		return err
+	}
 
+	source, ok := sourceAs[ResourceGetter](res.source)
 	if !ok {
-		return fmt.Errorf("Resource %s does not implement the ResourceCreator interface", res.name)
+		return fmt.Errorf("Resource %s does not implement the ResourceGetter interface", res.name)
 	}
 
-	ctx, err := unmarshalRequest(r)
+	response, err := source.FindOne(id, buildRequest(c, r))
 	if err != nil {
 		return err
 	}
 
-	// Ok this is weird again, but reflect.New produces a pointer, so we need the pure type without pointer,
-	// otherwise we would have a pointer pointer type that we don't want.
-	resourceType := res.resourceType
-	if resourceType.Kind() == reflect.Ptr {
-		resourceType = resourceType.Elem()
+	if response.Result() == nil {
+		return NewHTTPError(nil, fmt.Sprintf("Resource %s with id %s does not exist", res.name, id), http.StatusNotFound)
 	}
-	newObj := reflect.New(resourceType).Interface()
 
-	// Call InitializeObject if available to allow implementers change the object
-	// before calling Unmarshal.
-	if initSource, ok := source.(ObjectInitializer); ok {
-		initSource.InitializeObject(newObj)
+	parent, ok := response.Result().(jsonapi.MarshalLinkedRelations)
+	if !ok {
+		return fmt.Errorf("Resource %s does not implement the MarshalLinkedRelations interface", res.name)
+	}
+
+	references := []jsonapi.ReferenceID{}
+	for _, reference := range parent.GetReferencedIDs() {
+		if reference.Name != linked.Name {
+			continue
+		}
+
+		if !containsString(linked.Types, reference.Type) {
+			return fmt.Errorf("Relationship %s can not reference resources of type %s", linked.Name, reference.Type)
+		}
+
+		references = append(references, reference)
+	}
+
+	var links jsonapi.Links
+	toMany := isToManyReference(linked)
+	if pagination := newPaginationQueryParams(r); toMany && pagination.isValid() {
+		start, end, err := pagination.bounds(len(references))
+		if err != nil {
+			return NewHTTPError(err, "Invalid pagination query parameters", http.StatusBadRequest)
+		}
+
+		links, err = pagination.getLinks(r, uint(len(references)), info)
+		if err != nil {
+			return NewHTTPError(err, "Invalid pagination query parameters", http.StatusBadRequest)
+		}
+
+		references = references[start:end]
 	}
 
-	err = jsonapi.Unmarshal(ctx, newObj)
+	related, err := res.loadReferences(c, api, r, id, linked, references)
 	if err != nil {
-		return NewHTTPError(nil, err.Error(), http.StatusNotAcceptable)
+		return err
 	}
 
-	var response Responder
+	var result interface{} = related
+	if !toMany {
+		switch {
+		case len(references) == 0:
+			result = nil
+		case len(related) == 0:
+			return NewHTTPError(nil, fmt.Sprintf("Related resource %s with id %s does not exist", references[0].Type, references[0].ID), http.StatusNotFound)
+		case len(related) == 1:
+			result = related[0]
+		default:
+			return fmt.Errorf("found %d resources for the to-one relationship %s", len(related), linked.Name)
+		}
+	}
 
-	if res.resourceType.Kind() == reflect.Struct {
-		// we have to dereference the pointer if user wants to use non pointer values
-		response, err = source.Create(reflect.ValueOf(newObj).Elem().Interface(), buildRequest(c, r))
-	} else {
-		response, err = source.Create(newObj, buildRequest(c, r))
+	doc, err := api.marshalDocument(c, result, info, nil, links, r)
+	if err != nil {
+		return err
+	}
+
+	return res.marshalResponse(doc, "", w, http.StatusOK, r)
+}
+
+// loadReferences loads the referenced resources of a relationship of the
+// resource with the given id in the order of references. Types whose source
+// implements RelatedResourceFinder are loaded with one call to FindRelated,
+// all other resources with FindOne. Resources that do not exist are skipped.
+func (res *resource) loadReferences(c APIContexter, api *API, r *http.Request, id string, linked jsonapi.Reference, references []jsonapi.ReferenceID) ([]jsonapi.MarshalIdentifier, error) {
+	loaded := map[string]jsonapi.MarshalIdentifier{}
+	foundRelated := map[string]bool{}
+	for _, reference := range references {
+		key := reference.Type + "/" + reference.ID
+		if _, ok := loaded[key]; ok || foundRelated[reference.Type] {
+			continue
+		}
+
+		target := api.findResource(reference.Type)
+		if target == nil {
+			return nil, fmt.Errorf("No resource handler is registered to handle the linked resource type %s", reference.Type)
+		}
+
+		if finder, ok := sourceAs[RelatedResourceFinder](target.source); ok {
+			foundRelated[reference.Type] = true
+			response, err := finder.FindRelated(res.name, id, linked.Name, buildRequest(c, r))
+			if err != nil {
+				return nil, err
+			}
+
+			if response != nil {
+				for _, obj := range toMarshalIdentifiers(response.Result()) {
+					loaded[reference.Type+"/"+obj.GetID()] = obj
+				}
+			}
+			continue
+		}
+
+		getter, ok := sourceAs[ResourceGetter](target.source)
+		if !ok {
+			return nil, fmt.Errorf("Resource %s does not implement the ResourceGetter interface", target.name)
+		}
+
+		loaded[key] = nil
+		response, err := getter.FindOne(reference.ID, buildRequest(c, r))
+		var httpError HTTPError
+		if errors.As(err, &httpError) && httpError.status == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if objs := toMarshalIdentifiers(response.Result()); len(objs) > 0 {
+			loaded[key] = objs[0]
+		}
+	}
+
+	related := []jsonapi.MarshalIdentifier{}
+	for _, reference := range references {
+		if obj := loaded[reference.Type+"/"+reference.ID]; obj != nil {
+			related = append(related, obj)
+		}
+	}
+
+	return related, nil
+}
+
+// respondWithRelated writes the related resources of a relationship, to-one
+// relationships respond with a single resource or null
+func (res *resource) respondWithRelated(c APIContexter, obj Responder, linked jsonapi.Reference, info information, w http.ResponseWriter, r *http.Request) error {
+	if !isToManyReference(linked) {
+		result := reflect.ValueOf(obj.Result())
+		if result.Kind() == reflect.Slice {
+			switch result.Len() {
+			case 0:
+				obj = relatedResponder{obj, nil}
+			case 1:
+				obj = relatedResponder{obj, result.Index(0).Interface()}
+			default:
+				return fmt.Errorf("found %d resources for the to-one relationship %s", result.Len(), linked.Name)
+			}
+		}
 	}
+
+	return res.respondWith(c, obj, info, http.StatusOK, w, r)
+}
+
+// relatedResponder replaces the result of a Responder
+type relatedResponder struct {
+	Responder
+	result interface{}
+}
+
+func (r relatedResponder) Result() interface{} {
+	return r.result
+}
+
+func (res *resource) handleCreate(c APIContexter, w http.ResponseWriter, r *http.Request, prefix string, info information) error {
+	ctx, err := unmarshalRequest(r)
+	if err != nil {
+		return err
+	}
+
+	if items, ok := bulkItems(ctx); ok {
+		return res.handleBulkCreate(c, w, r, items, info)
+	}
+
+	source, ok := sourceAs[ResourceCreator](res.source)
+
+	if !ok {
+		return fmt.Errorf("Resource %s does not implement the ResourceCreator interface", res.name)
+	}
+
+	if err := res.checkIdentifier(ctx, ""); err != nil {
+		return err
+	}
+
+	newObj, err := res.unmarshalNew(ctx)
+	if err != nil {
+		return unmarshalError(err)
+	}
+
+	if err := validate(newObj); err != nil {
+		return err
+	}
+
+	response, err := source.Create(newObj, buildRequest(c, r))
 	if err != nil {
 		return err
 	}
@@ -724,7 +1035,7 @@ func (res *resource) handleCreate(c APIContexter, w http.ResponseWriter, r *http
 	// handle 200 status codes
 	switch response.StatusCode() {
 	case http.StatusCreated:
-		return res.respondWith(response, info, http.StatusCreated, w, r)
+		return res.respondWith(c, response, info, http.StatusCreated, w, r)
 	case http.StatusNoContent:
 		w.WriteHeader(response.StatusCode())
 		return nil
@@ -737,44 +1048,47 @@ func (res *resource) handleCreate(c APIContexter, w http.ResponseWriter, r *http
 }
 
 func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
-	source, ok := res.source.(ResourceUpdater)
+	source, ok := sourceAs[ResourceUpdater](res.source)
 
 	if !ok {
 		return fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
 	}
 
 	id := params["id"]
-	obj, err := source.FindOne(id, buildRequest(c, r))
+	ctx, err := unmarshalRequest(r)
 	if err != nil {
 		return err
 	}
 
-	ctx, err := unmarshalRequest(r)
+	if err := res.checkIdentifier(ctx, id); err != nil {
+		return err
+	}
+
+	obj, err := source.FindOne(id, buildRequest(c, r))
 	if err != nil {
 		return err
 	}
 
-	// we have to make the Result to a pointer to unmarshal into it
-	updatingObj := reflect.ValueOf(obj.Result())
-	if updatingObj.Kind() == reflect.Struct {
-		updatingObjPtr := reflect.New(reflect.TypeOf(obj.Result()))
-		updatingObjPtr.Elem().Set(updatingObj)
-		err = jsonapi.Unmarshal(ctx, updatingObjPtr.Interface())
-		updatingObj = updatingObjPtr.Elem()
-	} else {
-		err = jsonapi.Unmarshal(ctx, updatingObj.Interface())
+	if err := res.checkIfMatch(c, obj, info, r); err != nil {
+		return err
 	}
+
+	updatingObj, err := unmarshalExisting(obj.Result(), ctx)
 	if err != nil {
-		return NewHTTPError(nil, err.Error(), http.StatusNotAcceptable)
+		return unmarshalError(err)
 	}
 
-	identifiable, ok := updatingObj.Interface().(jsonapi.MarshalIdentifier)
+	identifiable, ok := updatingObj.(jsonapi.MarshalIdentifier)
 	if !ok || identifiable.GetID() != id {
 		conflictError := errors.New("id in the resource does not match servers endpoint")
 		return NewHTTPError(conflictError, conflictError.Error(), http.StatusConflict)
 	}
 
-	response, err := source.Update(updatingObj.Interface(), buildRequest(c, r))
+	if err := validate(updatingObj); err != nil {
+		return err
+	}
+
+	response, err := source.Update(updatingObj, buildRequest(c, r))
 
 	if err != nil {
 		return err
@@ -796,7 +1110,7 @@ func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http
 			response = internalResponse
 		}
 
-		return res.respondWith(response, info, http.StatusOK, w, r)
+		return res.respondWith(c, response, info, http.StatusOK, w, r)
 	case http.StatusAccepted:
 		w.WriteHeader(http.StatusAccepted)
 		return nil
@@ -808,25 +1122,11 @@ func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http
 	}
 }
 
-func (res *resource) handleReplaceRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, relation jsonapi.Reference) error {
-	source, ok := res.source.(ResourceUpdater)
-
-	if !ok {
-		return fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
-	}
-
-	var (
-		err     error
-		editObj interface{}
-	)
-
+// handleRelationshipUpdate replaces, adds or deletes members of a
+// relationship with the RelationshipUpdater of the source or, if the source
+// does not implement it, with FindOne and Update.
+func (res *resource) handleRelationshipUpdate(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information, relation jsonapi.Reference, action Action) error {
 	id := params["id"]
-
-	response, err := source.FindOne(id, buildRequest(c, r))
-	if err != nil {
-		return err
-	}
-
 	body, err := unmarshalRequest(r)
 	if err != nil {
 		return err
@@ -835,89 +1135,134 @@ func (res *resource) handleReplaceRelation(c APIContexter, w http.ResponseWriter
 	inc := map[string]interface{}{}
 	err = json.Unmarshal(body, &inc)
 	if err != nil {
-		return err
+		return NewHTTPError(err, err.Error(), http.StatusBadRequest)
 	}
+
 	data, ok := inc["data"]
 	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
+		return newSourceError(nil, http.StatusBadRequest, "Invalid object. Need a \"data\" object", "/data")
 	}
 
-	resType := reflect.TypeOf(response.Result()).Kind()
-	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
-	} else {
-		editObj = response.Result()
+	if err := checkLinkageTypes(data, relation); err != nil {
+		return err
 	}
 
-	err = processRelationshipsData(data, relation.Name, editObj)
+	var response Responder
+	if updater, ok := relationshipUpdater(res.source); ok {
+		response, err = res.updateRelationship(c, updater, r, id, info, relation, action, data)
+	} else {
+		response, err = res.updateRelationshipWithSource(c, r, id, info, relation, action, data)
+	}
 	if err != nil {
 		return err
 	}
 
-	if resType == reflect.Struct {
-		_, err = source.Update(reflect.ValueOf(editObj).Elem().Interface(), buildRequest(c, r))
-	} else {
-		_, err = source.Update(editObj, buildRequest(c, r))
+	status := http.StatusNoContent
+	if response != nil && response.StatusCode() != 0 {
+		status = response.StatusCode()
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-	return err
-}
+	switch status {
+	case http.StatusOK:
+		if response.Result() == nil {
+			getter, ok := sourceAs[ResourceGetter](res.source)
+			if !ok {
+				return fmt.Errorf("Resource %s does not implement the ResourceGetter interface", res.name)
+			}
 
-func (res *resource) handleAddToManyRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, relation jsonapi.Reference) error {
-	source, ok := res.source.(ResourceUpdater)
+			response, err = getter.FindOne(id, buildRequest(c, r))
+			if err != nil {
+				return err
+			}
+		}
 
-	if !ok {
-		return fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
+		return res.respondWithRelationship(response, info, relation, w, r)
+	case http.StatusAccepted, http.StatusNoContent:
+		w.WriteHeader(status)
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for method %s", status, res.name, action)
 	}
+}
 
-	var (
-		err     error
-		editObj interface{}
-	)
+// updateRelationship passes the relationship update to the RelationshipUpdater
+// or PolymorphicRelationshipUpdater of the source
+func (res *resource) updateRelationship(c APIContexter, updater PolymorphicRelationshipUpdater, r *http.Request, id string, info information, relation jsonapi.Reference, action Action, data interface{}) (Responder, error) {
+	if err := res.checkIfMatchByID(c, id, info, r); err != nil {
+		return nil, err
+	}
 
-	id := params["id"]
+	if action == ActionReplaceRelationship {
+		references, _, err := relationshipReferences(data, relation.Name)
+		if err != nil {
+			return nil, err
+		}
 
-	response, err := source.FindOne(id, buildRequest(c, r))
-	if err != nil {
-		return err
+		return updater.ReplaceRelationshipReferences(id, relation.Name, references, buildRequest(c, r))
 	}
 
-	body, err := unmarshalRequest(r)
+	references, err := toManyReferences(data)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	inc := map[string]interface{}{}
-	err = json.Unmarshal(body, &inc)
-	if err != nil {
-		return err
+
+	if action == ActionAddToManyRelationship {
+		return updater.AddToManyRelationshipReferences(id, relation.Name, references, buildRequest(c, r))
 	}
 
-	data, ok := inc["data"]
-	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
+	return updater.DeleteToManyRelationshipReferences(id, relation.Name, references, buildRequest(c, r))
+}
+
+// relationshipUpdater returns the PolymorphicRelationshipUpdater of source or
+// adapts its RelationshipUpdater, which only receives the ids
+func relationshipUpdater(source interface{}) (PolymorphicRelationshipUpdater, bool) {
+	if updater, ok := sourceAs[PolymorphicRelationshipUpdater](source); ok {
+		return updater, true
 	}
 
-	newRels, ok := data.([]interface{})
-	if !ok {
-		return fmt.Errorf("Data must be an array with \"id\" and \"type\" field to add new to-many relationships")
+	if updater, ok := sourceAs[RelationshipUpdater](source); ok {
+		return idRelationshipUpdater{updater}, true
 	}
 
-	newIDs := []string{}
+	return nil, false
+}
 
-	for _, newRel := range newRels {
-		casted, ok := newRel.(map[string]interface{})
-		if !ok {
-			return errors.New("entry in data object invalid")
-		}
-		newID, ok := casted["id"].(string)
-		if !ok {
-			return errors.New("no id field found inside data object")
-		}
+// idRelationshipUpdater passes only the ids of the references to a
+// RelationshipUpdater
+type idRelationshipUpdater struct {
+	updater RelationshipUpdater
+}
+
+func (u idRelationshipUpdater) ReplaceRelationshipReferences(ID, name string, references []jsonapi.ReferenceID, req Request) (Responder, error) {
+	return u.updater.ReplaceRelationship(ID, name, referenceIDs(references), req)
+}
+
+func (u idRelationshipUpdater) AddToManyRelationshipReferences(ID, name string, references []jsonapi.ReferenceID, req Request) (Responder, error) {
+	return u.updater.AddToManyRelationship(ID, name, referenceIDs(references), req)
+}
+
+func (u idRelationshipUpdater) DeleteToManyRelationshipReferences(ID, name string, references []jsonapi.ReferenceID, req Request) (Responder, error) {
+	return u.updater.DeleteToManyRelationship(ID, name, referenceIDs(references), req)
+}
+
+// updateRelationshipWithSource changes the relationship of the resource
+// returned by FindOne and saves it with Update
+func (res *resource) updateRelationshipWithSource(c APIContexter, r *http.Request, id string, info information, relation jsonapi.Reference, action Action, data interface{}) (Responder, error) {
+	source, ok := sourceAs[ResourceUpdater](res.source)
+	if !ok {
+		return nil, fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
+	}
+
+	response, err := source.FindOne(id, buildRequest(c, r))
+	if err != nil {
+		return nil, err
+	}
 
-		newIDs = append(newIDs, newID)
+	if err := res.checkIfMatch(c, response, info, r); err != nil {
+		return nil, err
 	}
 
+	var editObj interface{}
 	resType := reflect.TypeOf(response.Result()).Kind()
 	if resType == reflect.Struct {
 		editObj = getPointerToStruct(response.Result())
@@ -925,100 +1270,184 @@ func (res *resource) handleAddToManyRelation(c APIContexter, w http.ResponseWrit
 		editObj = response.Result()
 	}
 
-	targetObj, ok := editObj.(jsonapi.EditToManyRelations)
-	if !ok {
-		return errors.New("target struct must implement jsonapi.EditToManyRelations")
+	if action == ActionReplaceRelationship {
+		err = processRelationshipsData(data, relation.Name, editObj)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		references, err := toManyReferences(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := editToManyReferences(editObj, relation, action == ActionAddToManyRelationship, references); err != nil {
+			return nil, err
+		}
 	}
-	targetObj.AddToManyIDs(relation.Name, newIDs)
 
 	if resType == reflect.Struct {
-		_, err = source.Update(reflect.ValueOf(targetObj).Elem().Interface(), buildRequest(c, r))
-	} else {
-		_, err = source.Update(targetObj, buildRequest(c, r))
+		return source.Update(reflect.ValueOf(editObj).Elem().Interface(), buildRequest(c, r))
 	}
 
-	w.WriteHeader(http.StatusNoContent)
-
-	return err
+	return source.Update(editObj, buildRequest(c, r))
 }
 
-func (res *resource) handleDeleteToManyRelation(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, relation jsonapi.Reference) error {
-	source, ok := res.source.(ResourceUpdater)
+// respondWithRelationship writes the linkage of the relationship of obj
+func (res *resource) respondWithRelationship(obj Responder, info information, relation jsonapi.Reference, w http.ResponseWriter, r *http.Request) error {
+	document, err := jsonapi.MarshalToStructWithOptions(obj.Result(), marshalOptions(info))
+	if err != nil {
+		return err
+	}
 
+	rel, ok := document.Data.DataObject.Relationships[relation.Name]
 	if !ok {
-		return fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
+		return NewHTTPError(nil, fmt.Sprintf("There is no relation with the name %s", relation.Name), http.StatusNotFound)
 	}
 
-	var (
-		err     error
-		editObj interface{}
-	)
+	meta := obj.Metadata()
+	if len(meta) > 0 {
+		rel.Meta = meta
+	}
 
-	id := params["id"]
+	return res.marshalResponse(rel, "", w, http.StatusOK, withAppliedProfiles(r, obj))
+}
 
-	response, err := source.FindOne(id, buildRequest(c, r))
-	if err != nil {
-		return err
+// editsToManyRelation returns true if members can be added to and deleted
+// from the relationship
+func (res *resource) editsToManyRelation(relation jsonapi.Reference) bool {
+	if relation.Name != jsonapi.Pluralize(relation.Name) {
+		return false
 	}
 
-	body, err := unmarshalRequest(r)
-	if err != nil {
-		return err
+	if _, ok := res.ptrPrototype().(jsonapi.EditToManyRelations); ok {
+		return true
 	}
 
-	inc := map[string]interface{}{}
-	err = json.Unmarshal(body, &inc)
-	if err != nil {
-		return err
+	if _, ok := res.ptrPrototype().(jsonapi.EditPolymorphicToManyRelations); ok {
+		return true
 	}
 
-	data, ok := inc["data"]
-	if !ok {
-		return errors.New("Invalid object. Need a \"data\" object")
+	_, ok := relationshipUpdater(res.source)
+	return ok
+}
+
+// checkIdentifier compares the type of the primary data in body with the
+// endpoint before body is unmarshalled and applies the ClientIDPolicy to
+// create requests, which have an empty id. Invalid documents are left to
+// jsonapi.Unmarshal.
+func (res *resource) checkIdentifier(body []byte, id string) error {
+	document := struct {
+		Data *struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &document); err != nil || document.Data == nil {
+		return nil
 	}
 
-	newRels, ok := data.([]interface{})
-	if !ok {
-		return fmt.Errorf("Data must be an array with \"id\" and \"type\" field to add new to-many relationships")
+	data := document.Data
+	if data.Type == "" {
+		return nil
+	}
+
+	if data.Type != res.name {
+		return newSourceError(nil, http.StatusConflict, fmt.Sprintf("Type %s does not match the endpoint type %s", data.Type, res.name), "/data/type")
 	}
 
-	obsoleteIDs := []string{}
+	if id == "" {
+		return res.checkClientID(data.ID)
+	}
 
-	for _, newRel := range newRels {
-		casted, ok := newRel.(map[string]interface{})
-		if !ok {
-			return errors.New("entry in data object invalid")
+	return nil
+}
+
+// checkClientID applies the ClientIDPolicy of the source to the id of a
+// resource that is about to be created
+func (res *resource) checkClientID(id string) error {
+	policy, ok := sourceAs[ClientIDPolicy](res.source)
+	if !ok {
+		return nil
+	}
+
+	switch policy.ClientIDs() {
+	case ClientIDsForbidden:
+		if id != "" {
+			return newSourceError(nil, http.StatusForbidden, fmt.Sprintf("Resource %s does not support client-generated ids", res.name), "/data/id")
 		}
-		obsoleteID, ok := casted["id"].(string)
-		if !ok {
-			return errors.New("no id field found inside data object")
+	case ClientIDsRequired:
+		if id == "" {
+			return newSourceError(nil, http.StatusForbidden, fmt.Sprintf("Resource %s requires client-generated ids", res.name), "/data")
 		}
+	}
 
-		obsoleteIDs = append(obsoleteIDs, obsoleteID)
+	return nil
+}
+
+// unmarshalNew unmarshals body into a new instance of the resource type. The
+// result is a struct or a pointer, depending on the prototype used in AddResource.
+func (res *resource) unmarshalNew(body []byte) (interface{}, error) {
+	newObj := res.newObject()
+
+	// Call InitializeObject if available to allow implementers change the object
+	// before calling Unmarshal.
+	if initSource, ok := sourceAs[ObjectInitializer](res.source); ok {
+		initSource.InitializeObject(newObj)
 	}
 
-	resType := reflect.TypeOf(response.Result()).Kind()
-	if resType == reflect.Struct {
-		editObj = getPointerToStruct(response.Result())
-	} else {
-		editObj = response.Result()
+	err := jsonapi.Unmarshal(body, newObj)
+	if err != nil {
+		return nil, err
 	}
 
-	targetObj, ok := editObj.(jsonapi.EditToManyRelations)
-	if !ok {
-		return errors.New("target struct must implement jsonapi.EditToManyRelations")
+	if res.resourceType.Kind() == reflect.Struct {
+		// we have to dereference the pointer if user wants to use non pointer values
+		return reflect.ValueOf(newObj).Elem().Interface(), nil
 	}
-	targetObj.DeleteToManyIDs(relation.Name, obsoleteIDs)
 
-	if resType == reflect.Struct {
-		_, err = source.Update(reflect.ValueOf(targetObj).Elem().Interface(), buildRequest(c, r))
-	} else {
-		_, err = source.Update(targetObj, buildRequest(c, r))
+	return newObj, nil
+}
+
+// newObject returns a pointer to a new instance of the resource type, new
+// jsonapi.Resource values keep the type and schema of the prototype.
+func (res *resource) newObject() interface{} {
+	switch prototype := res.prototype.(type) {
+	case *jsonapi.Resource:
+		return &jsonapi.Resource{Type: prototype.Type, Schema: prototype.Schema}
+	case jsonapi.Resource:
+		return &jsonapi.Resource{Type: prototype.Type, Schema: prototype.Schema}
+	}
+
+	// Ok this is weird again, but reflect.New produces a pointer, so we need the pure type without pointer,
+	// otherwise we would have a pointer pointer type that we don't want.
+	resourceType := res.resourceType
+	if resourceType.Kind() == reflect.Ptr {
+		resourceType = resourceType.Elem()
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	return reflect.New(resourceType).Interface()
+}
+
+// unmarshalExisting unmarshals body into an object returned by FindOne and
+// returns the updated object with the same kind as the given one.
+func unmarshalExisting(existing interface{}, body []byte) (interface{}, error) {
+	// we have to make the Result to a pointer to unmarshal into it
+	updatingObj := reflect.ValueOf(existing)
+	if updatingObj.Kind() == reflect.Struct {
+		updatingObjPtr := reflect.New(reflect.TypeOf(existing))
+		updatingObjPtr.Elem().Set(updatingObj)
+		if err := jsonapi.Unmarshal(body, updatingObjPtr.Interface()); err != nil {
+			return nil, err
+		}
+		return updatingObjPtr.Elem().Interface(), nil
+	}
 
-	return err
+	if err := jsonapi.Unmarshal(body, existing); err != nil {
+		return nil, err
+	}
+
+	return existing, nil
 }
 
 // returns a pointer to an interface{} struct
@@ -1029,14 +1458,17 @@ func getPointerToStruct(oldObj interface{}) interface{} {
 	return ptr.Interface()
 }
 
-func (res *resource) handleDelete(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error {
-	source, ok := res.source.(ResourceDeleter)
+func (res *resource) handleDelete(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string, info information) error {
+	source, ok := sourceAs[ResourceDeleter](res.source)
 
 	if !ok {
 		return fmt.Errorf("Resource %s does not implement the ResourceDeleter interface", res.name)
 	}
 
 	id := params["id"]
+	if err := res.checkIfMatchByID(c, id, info, r); err != nil {
+		return err
+	}
 	response, err := source.Delete(id, buildRequest(c, r))
 	if err != nil {
 		return err
@@ -1048,7 +1480,7 @@ func (res *resource) handleDelete(c APIContexter, w http.ResponseWriter, r *http
 			"meta": response.Metadata(),
 		}
 
-		return res.marshalResponse(data, w, http.StatusOK, r)
+		return res.marshalResponse(data, "", w, http.StatusOK, withAppliedProfiles(r, response))
 	case http.StatusAccepted:
 		w.WriteHeader(http.StatusAccepted)
 		return nil
@@ -1066,42 +1498,107 @@ func writeResult(w http.ResponseWriter, data []byte, status int, contentType str
 	w.Write(data)
 }
 
-func (res *resource) respondWith(obj Responder, info information, status int, w http.ResponseWriter, r *http.Request) error {
-	data, err := jsonapi.MarshalToStruct(obj.Result(), info)
+func (res *resource) respondWith(c APIContexter, obj Responder, info information, status int, w http.ResponseWriter, r *http.Request) error {
+	data, err := res.document(c, obj, info, r)
 	if err != nil {
 		return err
 	}
 
-	meta := obj.Metadata()
-	if len(meta) > 0 {
-		data.Meta = meta
-	}
+	etag, _ := versionETag(obj.Result(), r.URL.Query())
+	return res.marshalResponse(data, etag, w, status, withAppliedProfiles(r, obj))
+}
 
+// document returns the response document of obj with its meta and links
+func (res *resource) document(c APIContexter, obj Responder, info information, r *http.Request) (*jsonapi.Document, error) {
+	var links jsonapi.Links
 	if objWithLinks, ok := obj.(LinksResponder); ok {
 		baseURL := strings.Trim(info.GetBaseURL(), "/")
 		requestURL := fmt.Sprintf("%s%s", baseURL, r.URL.Path)
-		links := objWithLinks.Links(r, requestURL)
-		if len(links) > 0 {
-			data.Links = links
-		}
+		links = objWithLinks.Links(r, requestURL)
 	}
 
-	return res.marshalResponse(data, w, status, r)
+	return res.marshalDocument(c, obj.Result(), info, obj.Metadata(), links, r)
 }
 
-func (res *resource) respondWithPagination(obj Responder, info information, status int, links jsonapi.Links, w http.ResponseWriter, r *http.Request) error {
-	data, err := jsonapi.MarshalToStruct(obj.Result(), info)
+func (res *resource) respondWithPagination(c APIContexter, obj Responder, info information, status int, links jsonapi.Links, w http.ResponseWriter, r *http.Request) error {
+	data, err := res.marshalDocument(c, obj.Result(), info, obj.Metadata(), links, r)
 	if err != nil {
 		return err
 	}
 
-	data.Links = links
-	meta := obj.Metadata()
-	if len(meta) > 0 {
-		data.Meta = meta
+	return res.marshalResponse(data, "", w, status, withAppliedProfiles(r, obj))
+}
+
+func (res *resource) respondWithCursorPagination(c APIContexter, obj Responder, info information, pagination paginationQueryParams, page CursorPage, w http.ResponseWriter, r *http.Request) error {
+	meta := map[string]interface{}{}
+	for key, value := range obj.Metadata() {
+		meta[key] = value
+	}
+	if _, ok := meta["page"]; !ok {
+		meta["page"] = pagination.getCursorMeta(page)
 	}
 
-	return res.marshalResponse(data, w, status, r)
+	data, err := res.marshalDocument(c, obj.Result(), info, meta, pagination.getCursorLinks(r, page, info), r)
+	if err != nil {
+		return err
+	}
+
+	return res.marshalResponse(data, "", w, http.StatusOK, withAppliedProfiles(r, obj))
+}
+
+// marshalOptions returns the options all documents of the api are marshalled
+// with
+func marshalOptions(info information) jsonapi.MarshalOptions {
+	return jsonapi.MarshalOptions{
+		BaseURL:           info.GetBaseURL(),
+		Prefix:            info.GetPrefix(),
+		RelationshipLinks: true,
+		CustomLinks:       true,
+	}
+}
+
+// marshalDocument validates the requested include paths against res and
+// marshals result like API.marshalDocument.
+func (res *resource) marshalDocument(c APIContexter, result interface{}, info information, meta map[string]interface{}, links jsonapi.Links, r *http.Request) (*jsonapi.Document, error) {
+	if err := res.validateIncludes(r); err != nil {
+		return nil, err
+	}
+
+	return res.api.marshalDocument(c, result, info, meta, links, r)
+}
+
+// marshalDocument marshals result with the given top-level meta and links and
+// the included structs and sparse fieldsets requested by the query parameters.
+// The include paths must be validated before.
+func (api *API) marshalDocument(c APIContexter, result interface{}, info information, meta map[string]interface{}, links jsonapi.Links, r *http.Request) (*jsonapi.Document, error) {
+	options := marshalOptions(info)
+	query := r.URL.Query()
+	options.Fields = jsonapi.ParseQueryFields(&query)
+	options.Meta = meta
+	options.Links = links
+
+	if paths, ok := includePaths(r); ok {
+		options.Include = paths
+		options.ResolveInclude = api.resolveInclude(c, r)
+	}
+
+	data, err := jsonapi.MarshalToStructWithOptions(result, options)
+	invalidFields := &jsonapi.InvalidFieldsError{}
+	if errors.As(err, &invalidFields) {
+		return nil, sparseFieldsError(invalidFields)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// sparseFieldsError converts invalid sparse fieldsets into a 400 Bad Request
+func sparseFieldsError(err *jsonapi.InvalidFieldsError) HTTPError {
+	httpError := NewHTTPError(err, "Some requested fields were invalid", http.StatusBadRequest)
+	httpError.Errors = err.Errors()
+	return httpError
 }
 
 func unmarshalRequest(r *http.Request) ([]byte, error) {
@@ -1113,78 +1610,300 @@ func unmarshalRequest(r *http.Request) ([]byte, error) {
 	return data, nil
 }
 
-func handleError(err error, w http.ResponseWriter, r *http.Request, contentType string) {
-	log.Println(err)
-	if e, ok := err.(HTTPError); ok {
-		writeResult(w, []byte(marshalHTTPError(e)), e.Status(), contentType)
-		return
+// handleError reports the error to the error handler and writes it as
+// JSON:API error document
+func (api *API) handleError(w http.ResponseWriter, r *http.Request, info ErrorInfo) {
+	api.reportError(r, info)
+
+	e := api.httpError(info.Err)
+	writeResult(w, []byte(marshalHTTPError(e)), e.Status(), api.contentType(r))
+}
+
+// reportError passes an error to the error handler of the api or logs it
+func (api *API) reportError(r *http.Request, info ErrorInfo) {
+	if api.errorHandler != nil {
+		api.errorHandler(r, info)
+	} else {
+		api.logError(r, info)
+	}
+}
 
+// logError is the default error handler, it logs the error with the logger
+// of the api or slog.Default()
+func (api *API) logError(r *http.Request, info ErrorInfo) {
+	logger := api.logger
+	if logger == nil {
+		logger = slog.Default()
 	}
 
-	e := NewHTTPError(err, err.Error(), http.StatusInternalServerError)
-	writeResult(w, []byte(marshalHTTPError(e)), http.StatusInternalServerError, contentType)
+	logger.ErrorContext(r.Context(), "api2go request failed",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"resource", info.Resource,
+		"action", string(info.Action),
+		"error", info.Err,
+	)
+}
+
+// httpError returns err as HTTPError, all other errors are converted by the
+// error mapper or become a 500 Internal Server Error
+func (api *API) httpError(err error) HTTPError {
+	var e HTTPError
+	if errors.As(err, &e) {
+		return e
+	}
+
+	if api.errorMapper != nil {
+		return api.errorMapper(err)
+	}
+
+	return NewHTTPError(err, err.Error(), http.StatusInternalServerError)
 }
 
 // TODO: this can also be replaced with a struct into that we directly json.Unmarshal
+// processRelationshipsData sets the relationship linkage in data to target
 func processRelationshipsData(data interface{}, linkName string, target interface{}) error {
-	hasOne, ok := data.(map[string]interface{})
-	if ok {
-		hasOneID, ok := hasOne["id"].(string)
+	references, toMany, err := relationshipReferences(data, linkName)
+	if err != nil {
+		return err
+	}
+
+	if toMany {
+		if polymorphic, ok := target.(jsonapi.UnmarshalPolymorphicToManyRelations); ok {
+			return polymorphic.SetToManyReferences(linkName, references)
+		}
+
+		target, ok := target.(jsonapi.UnmarshalToManyRelations)
 		if !ok {
-			return fmt.Errorf("data object must have a field id for %s", linkName)
+			return newSourceError(nil, http.StatusBadRequest, "target struct must implement interface UnmarshalToManyRelations", "/data")
 		}
 
-		target, ok := target.(jsonapi.UnmarshalToOneRelations)
+		return target.SetToManyReferenceIDs(linkName, referenceIDs(references))
+	}
+
+	// an empty to-one relationship means that it must be deleted
+	reference := jsonapi.ReferenceID{Name: linkName, Relationship: jsonapi.ToOneRelationship}
+	if len(references) > 0 {
+		reference = references[0]
+	}
+
+	if polymorphic, ok := target.(jsonapi.UnmarshalPolymorphicToOneRelations); ok {
+		return polymorphic.SetToOneReference(linkName, reference)
+	}
+
+	toOne, ok := target.(jsonapi.UnmarshalToOneRelations)
+	if !ok {
+		return newSourceError(nil, http.StatusBadRequest, "target struct must implement interface UnmarshalToOneRelations", "/data")
+	}
+
+	return toOne.SetToOneReferenceID(linkName, reference.ID)
+}
+
+// relationshipReferences returns the resource identifiers of a relationship
+// linkage and whether it is a to-many linkage, empty to-one relationships have
+// no identifier
+func relationshipReferences(data interface{}, linkName string) ([]jsonapi.ReferenceID, bool, error) {
+	if data == nil {
+		return []jsonapi.ReferenceID{}, false, nil
+	}
+
+	if hasOne, ok := data.(map[string]interface{}); ok {
+		hasOneID, ok := hasOne["id"].(string)
 		if !ok {
-			return errors.New("target struct must implement interface UnmarshalToOneRelations")
+			return nil, false, newSourceError(nil, http.StatusBadRequest, fmt.Sprintf("data object must have a field id for %s", linkName), "/data/id")
 		}
 
-		err := target.SetToOneReferenceID(linkName, hasOneID)
-		if err != nil {
-			return err
+		hasOneType, _ := hasOne["type"].(string)
+		return []jsonapi.ReferenceID{{ID: hasOneID, Type: hasOneType, Name: linkName, Relationship: jsonapi.ToOneRelationship}}, false, nil
+	}
+
+	if _, ok := data.([]interface{}); !ok {
+		return nil, false, newSourceError(nil, http.StatusBadRequest, fmt.Sprintf("invalid data object or array, must be an object with \"id\" and \"type\" field for %s", linkName), "/data")
+	}
+
+	references, err := toManyReferences(data)
+	for i := range references {
+		references[i].Name = linkName
+	}
+
+	return references, true, err
+}
+
+// toManyReferences returns the resource identifier objects in the data array
+// of a to-many relationship request
+func toManyReferences(data interface{}) ([]jsonapi.ReferenceID, error) {
+	entries, ok := data.([]interface{})
+	if !ok {
+		return nil, newSourceError(nil, http.StatusBadRequest, "Data must be an array with \"id\" and \"type\" field to edit to-many relationships", "/data")
+	}
+
+	references := []jsonapi.ReferenceID{}
+	for i, entry := range entries {
+		casted, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, newSourceError(nil, http.StatusBadRequest, "entry in data object invalid", fmt.Sprintf("/data/%d", i))
 		}
-	} else if data == nil {
-		// this means that a to-one relationship must be deleted
-		target, ok := target.(jsonapi.UnmarshalToOneRelations)
+
+		id, ok := casted["id"].(string)
 		if !ok {
-			return errors.New("target struct must implement interface UnmarshalToOneRelations")
+			return nil, newSourceError(nil, http.StatusBadRequest, "no id field found inside data object", fmt.Sprintf("/data/%d/id", i))
 		}
 
-		err := target.SetToOneReferenceID(linkName, "")
-		if err != nil {
-			return err
+		resourceType, _ := casted["type"].(string)
+		references = append(references, jsonapi.ReferenceID{ID: id, Type: resourceType, Relationship: jsonapi.ToManyRelationship})
+	}
+
+	return references, nil
+}
+
+// toManyIDs returns the ids of the resource identifier objects in the data
+// array of a to-many relationship request
+func toManyIDs(data interface{}) ([]string, error) {
+	references, err := toManyReferences(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return referenceIDs(references), nil
+}
+
+func referenceIDs(references []jsonapi.ReferenceID) []string {
+	ids := make([]string, len(references))
+	for i, reference := range references {
+		ids[i] = reference.ID
+	}
+
+	return ids
+}
+
+// checkLinkageTypes returns 409 Conflict if the linkage of a polymorphic
+// relationship references types that are not part of the relationship
+func checkLinkageTypes(data interface{}, relation jsonapi.Reference) error {
+	if !relation.IsPolymorphic() {
+		return nil
+	}
+
+	references, toMany, err := relationshipReferences(data, relation.Name)
+	if err != nil {
+		return err
+	}
+
+	for i, reference := range references {
+		if containsString(relation.Types, reference.Type) {
+			continue
 		}
-	} else {
-		hasMany, ok := data.([]interface{})
-		if !ok {
-			return fmt.Errorf("invalid data object or array, must be an object with \"id\" and \"type\" field for %s", linkName)
+
+		pointer := "/data/type"
+		if toMany {
+			pointer = fmt.Sprintf("/data/%d/type", i)
 		}
 
-		target, ok := target.(jsonapi.UnmarshalToManyRelations)
-		if !ok {
-			return errors.New("target struct must implement interface UnmarshalToManyRelations")
+		return newSourceError(nil, http.StatusConflict, fmt.Sprintf("Relationship %s can not reference resources of type %s", relation.Name, reference.Type), pointer)
+	}
+
+	return nil
+}
+
+// editToManyReferences adds references to or deletes them from a to-many
+// relationship of target, EditPolymorphicToManyRelations is preferred over
+// EditToManyRelations. Polymorphic relationships of targets that only
+// implement UnmarshalPolymorphicToManyRelations are replaced as a whole.
+func editToManyReferences(target interface{}, relation jsonapi.Reference, add bool, references []jsonapi.ReferenceID) error {
+	if polymorphic, ok := target.(jsonapi.EditPolymorphicToManyRelations); ok {
+		if add {
+			return polymorphic.AddToManyReferences(relation.Name, references)
 		}
+		return polymorphic.DeleteToManyReferences(relation.Name, references)
+	}
 
-		hasManyIDs := []string{}
+	if polymorphic, ok := target.(jsonapi.UnmarshalPolymorphicToManyRelations); ok && relation.IsPolymorphic() {
+		return polymorphic.SetToManyReferences(relation.Name, editPolymorphicReferences(target, relation.Name, references, add))
+	}
 
-		for _, entry := range hasMany {
-			data, ok := entry.(map[string]interface{})
-			if !ok {
-				return fmt.Errorf("entry in data array must be an object for %s", linkName)
+	targetObj, ok := target.(jsonapi.EditToManyRelations)
+	if !ok {
+		return errors.New("target struct must implement jsonapi.EditToManyRelations")
+	}
+
+	if add {
+		return targetObj.AddToManyIDs(relation.Name, referenceIDs(references))
+	}
+
+	return targetObj.DeleteToManyIDs(relation.Name, referenceIDs(references))
+}
+
+// editPolymorphicReferences returns the references of a polymorphic to-many
+// relationship of obj after adding or deleting the given references
+func editPolymorphicReferences(obj interface{}, name string, references []jsonapi.ReferenceID, add bool) []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{}
+	if linked, ok := obj.(jsonapi.MarshalLinkedRelations); ok {
+		for _, current := range linked.GetReferencedIDs() {
+			if current.Name != name {
+				continue
 			}
-			dataID, ok := data["id"].(string)
-			if !ok {
-				return fmt.Errorf("all data objects must have a field id for %s", linkName)
+
+			deleted := false
+			for _, reference := range references {
+				if !add && reference.ID == current.ID && reference.Type == current.Type {
+					deleted = true
+					break
+				}
 			}
 
-			hasManyIDs = append(hasManyIDs, dataID)
+			if !deleted {
+				result = append(result, current)
+			}
 		}
+	}
 
-		err := target.SetToManyReferenceIDs(linkName, hasManyIDs)
-		if err != nil {
-			return err
+	if add {
+		for _, reference := range references {
+			reference.Name = name
+			result = append(result, reference)
 		}
 	}
 
-	return nil
+	return result
+}
+
+// newSourceError creates an HTTPError with one error object that points to
+// the invalid member of the request document
+func newSourceError(err error, status int, title, pointer string) HTTPError {
+	httpError := NewHTTPError(err, title, status)
+	httpError.Errors = []jsonapi.Error{{
+		Status: strconv.Itoa(status),
+		Title:  title,
+	}}
+	if pointer != "" {
+		httpError.Errors[0].Source = &jsonapi.ErrorSource{Pointer: pointer}
+	}
+
+	return httpError
+}
+
+// unmarshalError converts an error of jsonapi.Unmarshal into an HTTPError with
+// the matching status code and source pointer
+func unmarshalError(err error) error {
+	var httpError HTTPError
+	if errors.As(err, &httpError) {
+		return httpError
+	}
+
+	var unmarshalErr *jsonapi.UnmarshalError
+	if errors.As(err, &unmarshalErr) {
+		status := http.StatusBadRequest
+		if errors.Is(err, jsonapi.ErrTypeMismatch) {
+			status = http.StatusConflict
+		}
+
+		return newSourceError(err, status, unmarshalErr.Detail, unmarshalErr.Pointer)
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		title := fmt.Sprintf("Invalid %s value for attribute %s", typeError.Value, typeError.Field)
+		return newSourceError(err, http.StatusBadRequest, title, "/data/attributes/"+strings.ReplaceAll(typeError.Field, ".", "/"))
+	}
+
+	return NewHTTPError(err, err.Error(), http.StatusBadRequest)
 }
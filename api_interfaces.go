@@ -53,6 +53,43 @@ type ResourceUpdater interface {
 	Update(obj interface{}, req Request) (Responder, error)
 }
 
+// The BulkCreator interface can be optionally implemented to create multiple
+// resources with one POST request whose data is an array. objs contains the
+// unmarshalled resources in the order of the request, like the obj of Create.
+// Errors of single resources can be returned as BulkErrors.
+// Possible Responder status codes are:
+// - 201 Created: Resources were created, Result() must contain all of them
+// - 202 Accepted: Processing is delayed, return nothing
+// - 204 No Content: Resources were created as sent, return nothing
+type BulkCreator interface {
+	BulkCreate(objs []interface{}, req Request) (Responder, error)
+}
+
+// The BulkUpdater interface can be optionally implemented to update multiple
+// resources with one PATCH request to the collection whose data is an array.
+// Every resource is loaded with FindOne before the request is applied to it.
+// Errors of single resources can be returned as BulkErrors.
+// Possible Responder status codes are:
+// - 200 OK: Update successful, Result() must contain all updated resources
+// - 202 Accepted: Processing is delayed, return nothing
+// - 204 No Content: Update was successful, return nothing
+type BulkUpdater interface {
+	ResourceGetter
+	BulkUpdate(objs []interface{}, req Request) (Responder, error)
+}
+
+// The BulkDeleter interface can be optionally implemented to delete multiple
+// resources with one DELETE request to the collection whose data is an array
+// of resource identifiers. Errors of single resources can be returned as
+// BulkErrors.
+// Possible Responder status codes are:
+// - 200 OK: Deletion was a success, returns meta information
+// - 202 Accepted: Processing is delayed, return nothing
+// - 204 No Content: Deletion was successful, return nothing
+type BulkDeleter interface {
+	BulkDelete(ids []string, req Request) (Responder, error)
+}
+
 // Pagination represents information needed to return pagination links
 type Pagination struct {
 	Next  map[string]string
@@ -70,12 +107,46 @@ type PaginatedFindAll interface {
 	PaginatedFindAll(req Request) (totalCount uint, response Responder, err error)
 }
 
+// CursorPage contains the opaque cursors of the pages before and after the
+// current page. An empty cursor means that there is no such page.
+type CursorPage struct {
+	Next string
+	Prev string
+}
+
+// The CursorPaginatedFindAll interface can be optionally implemented to paginate
+// with cursors instead of page numbers or offsets, so no total count is needed.
+// It is used if the request contains page[after] or page[before], or only
+// page[size] to fetch the first page. The api generates page[after] and
+// page[before] links from the returned cursors and adds them to `meta.page`.
+type CursorPaginatedFindAll interface {
+	CursorPaginatedFindAll(req Request) (page CursorPage, response Responder, err error)
+}
+
 // The FindAll interface can be optionally implemented to fetch all records at once.
 type FindAll interface {
 	// FindAll returns all objects
 	FindAll(req Request) (Responder, error)
 }
 
+// The SortableResource interface can be optionally implemented to declare the
+// fields a resource can be sorted by. Requests sorting by any other field are
+// rejected with 400 Bad Request before FindAll or PaginatedFindAll is called.
+// The parsed sort order is available in Request.Sort.
+type SortableResource interface {
+	SortableFields() []string
+}
+
+// The FilterableResource interface can be optionally implemented to declare the
+// fields a resource can be filtered by, together with the supported operators
+// of each field, e.g. {"name": {"eq"}, "age": {"eq", "gt", "lt"}}. Filters without
+// an explicit operator use DefaultFilterOperator. Requests using any other
+// filter are rejected with 400 Bad Request before FindAll or PaginatedFindAll
+// is called. The parsed filters are available in Request.Filters.
+type FilterableResource interface {
+	FilterableFields() map[string][]string
+}
+
 // The ObjectInitializer interface can be implemented to have the ability to change
 // a created object before Unmarshal is called. This is currently only called on
 // Create as the other actions go through FindOne or FindAll which are already
@@ -84,6 +155,60 @@ type ObjectInitializer interface {
 	InitializeObject(interface{})
 }
 
+// The RelatedResourceFinder interface can be optionally implemented by the
+// source of a related resource to find the resources of the relationship
+// relation of the resource with the type parentType and the id parentID, e.g.
+// FindRelated("posts", "1", "comments", req) for GET /posts/1/comments. Without
+// it, FindAll is called with the query parameters <parentType>ID and
+// <parentType>Name. The Result() of to-one relationships is a single resource
+// or nil, the Result() of to-many relationships is a slice.
+type RelatedResourceFinder interface {
+	FindRelated(parentType, parentID, relation string, req Request) (Responder, error)
+}
+
+// The RelationshipUpdater interface can be optionally implemented to update
+// relationships directly instead of loading the resource with FindOne and
+// saving it with Update. IDs contains at most one id for to-one relationships
+// and is empty if the relationship is cleared.
+// Possible Responder status codes are:
+// - 200 OK: The relationship was updated, the linkage of Result() is returned.
+// If Result() is nil, the resource is loaded with FindOne
+// - 202 Accepted: Processing is delayed, return nothing
+// - 204 No Content: The relationship was updated as requested, return nothing
+type RelationshipUpdater interface {
+	ReplaceRelationship(ID, name string, IDs []string, req Request) (Responder, error)
+	AddToManyRelationship(ID, name string, IDs []string, req Request) (Responder, error)
+	DeleteToManyRelationship(ID, name string, IDs []string, req Request) (Responder, error)
+}
+
+// The PolymorphicRelationshipUpdater interface can be implemented instead of
+// RelationshipUpdater to receive the type of every resource identifier, e.g.
+// for polymorphic relationships. The status codes are the same.
+type PolymorphicRelationshipUpdater interface {
+	ReplaceRelationshipReferences(ID, name string, references []jsonapi.ReferenceID, req Request) (Responder, error)
+	AddToManyRelationshipReferences(ID, name string, references []jsonapi.ReferenceID, req Request) (Responder, error)
+	DeleteToManyRelationshipReferences(ID, name string, references []jsonapi.ReferenceID, req Request) (Responder, error)
+}
+
+// ClientIDMode defines whether clients may send the id of a created resource
+type ClientIDMode int
+
+const (
+	// ClientIDsAllowed accepts created resources with and without id
+	ClientIDsAllowed ClientIDMode = iota
+	// ClientIDsForbidden rejects created resources with an id
+	ClientIDsForbidden
+	// ClientIDsRequired rejects created resources without an id
+	ClientIDsRequired
+)
+
+// The ClientIDPolicy interface can be optionally implemented to control
+// client-generated ids. Create requests violating the policy are rejected with
+// 403 Forbidden before Create is called. Sources without a policy allow them.
+type ClientIDPolicy interface {
+	ClientIDs() ClientIDMode
+}
+
 // URLResolver allows you to implement a static
 // way to return a baseURL for all incoming
 // requests for one api2go instance.
@@ -123,3 +248,11 @@ type LinksResponder interface {
 	Responder
 	Links(*http.Request, string) jsonapi.Links
 }
+
+// The ProfilesResponder interface may be used when the response object applies
+// JSON:API profiles. Only the applied profiles the client asked for are
+// advertised in the Content-Type header of the response.
+type ProfilesResponder interface {
+	Responder
+	AppliedProfiles() []string
+}
@@ -17,19 +17,25 @@ type API struct {
 	ContentType      string
 	router           routing.Routeable
 	info             information
-	resources        []resource
+	resources        []*resource
 	middlewares      []HandlerFunc
+	hooks            []ResponseHookFunc
+	logger           Logger
+	errorHandler     ErrorHandlerFunc
+	errorMapper      ErrorMapperFunc
 	contextPool      sync.Pool
 	contextAllocator APIContextAllocatorFunc
+	extensions       []string
+	profiles         []string
 }
 
 // Handler returns the http.Handler instance for the API.
-func (api API) Handler() http.Handler {
+func (api *API) Handler() http.Handler {
 	return api.router.Handler()
 }
 
 // Router returns the specified router on an api instance
-func (api API) Router() routing.Routeable {
+func (api *API) Router() routing.Routeable {
 	return api.router
 }
 
@@ -42,16 +48,52 @@ func (api *API) SetContextAllocator(allocator APIContextAllocatorFunc) {
 // At least the CRUD interface must be implemented, all the other interfaces are optional.
 // `resource` should be either an empty struct instance such as `Post{}` or a pointer to
 // a struct such as `&Post{}`. The same type will be used for constructing new elements.
-func (api *API) AddResource(prototype jsonapi.MarshalIdentifier, source interface{}) {
-	api.addResource(prototype, source)
+// The returned Resource can be used to register middlewares for this resource only.
+// AddResource panics if the `validate` tags of the struct are invalid.
+func (api *API) AddResource(prototype jsonapi.MarshalIdentifier, source interface{}) *Resource {
+	return &Resource{api.addResource(prototype, source)}
+}
+
+// AddSchemaResource registers a data source for resources that are described
+// by a schema instead of a struct, e.g. types that are configured at runtime.
+// The source receives and returns *jsonapi.Resource values of the schema.
+func (api *API) AddSchemaResource(schema *jsonapi.Schema, source interface{}) *Resource {
+	return api.AddResource(schema.NewResource(""), source)
 }
 
 // UseMiddleware registers middlewares that implement the api2go.HandlerFunc
-// Middleware is run before any generated routes.
+// Middleware is run before any generated routes. If a middleware writes a
+// response, e.g. 401 Unauthorized, the remaining middlewares and the route
+// are skipped.
 func (api *API) UseMiddleware(middleware ...HandlerFunc) {
 	api.middlewares = append(api.middlewares, middleware...)
 }
 
+// UseResponseHook registers hooks that are called after an api2go route wrote
+// its response, e.g. to record metrics or audit logs.
+func (api *API) UseResponseHook(hooks ...ResponseHookFunc) {
+	api.hooks = append(api.hooks, hooks...)
+}
+
+// SetLogger sets the logger of the default error handler, e.g. a *slog.Logger.
+// slog.Default() is used if no logger is set.
+func (api *API) SetLogger(logger Logger) {
+	api.logger = logger
+}
+
+// SetErrorHandler replaces the default error handler, which logs every error
+// returned by a route with the logger of the api.
+func (api *API) SetErrorHandler(handler ErrorHandlerFunc) {
+	api.errorHandler = handler
+}
+
+// SetErrorMapper sets a function that converts errors that are no HTTPError
+// into the HTTPError sent to the client. By default they become a 500
+// Internal Server Error with the error message as title.
+func (api *API) SetErrorMapper(mapper ErrorMapperFunc) {
+	api.errorMapper = mapper
+}
+
 // NewAPIVersion can be used to chain an additional API version to the routing of a previous
 // one. Use this if you have multiple version prefixes and want to combine all
 // your different API versions. This reuses the baseURL or URLResolver
@@ -134,12 +134,16 @@ func (p *Post) AddToManyIDs(name string, IDs []string) error {
 		for _, ID := range IDs {
 			p.Comments = append(p.Comments, Comment{ID: ID})
 		}
+
+		return nil
 	}
 
 	if name == "bananas" {
 		for _, ID := range IDs {
 			p.Bananas = append(p.Bananas, Banana{ID: ID})
 		}
+
+		return nil
 	}
 
 	return errors.New("There is no to-manyrelationship with the name " + name)
@@ -155,6 +159,8 @@ func (p *Post) DeleteToManyIDs(name string, IDs []string) error {
 				}
 			}
 		}
+
+		return nil
 	}
 
 	if name == "bananas" {
@@ -166,6 +172,8 @@ func (p *Post) DeleteToManyIDs(name string, IDs []string) error {
 				}
 			}
 		}
+
+		return nil
 	}
 	return errors.New("There is no to-manyrelationship with the name " + name)
 }
@@ -855,8 +863,8 @@ var _ = Describe("RestHandler", func() {
 			req, err := http.NewRequest("PATCH", "/v1/posts/1", reqBody)
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
-			Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
-			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"406","title":"invalid record, no type was specified"}]}`))
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"400","title":"invalid record, no type was specified","source":{"pointer":"/data/type"}}]}`))
 		})
 
 		It("patch must contain type and id but does not have id", func() {
@@ -869,16 +877,75 @@ var _ = Describe("RestHandler", func() {
 			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"409","title":"id in the resource does not match servers endpoint"}]}`))
 		})
 
-		It("POST without type returns 406", func() {
+		It("POST without type returns 400", func() {
 			reqBody := strings.NewReader(`{"data": {"title": "New Title"}}`)
 			req, err := http.NewRequest("POST", "/v1/posts", reqBody)
 			Expect(err).To(BeNil())
 			api.Handler().ServeHTTP(rec, req)
-			Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
-			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"406","title":"invalid record, no type was specified"}]}`))
+			Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			Expect(string(rec.Body.Bytes())).To(MatchJSON(`{"errors":[{"status":"400","title":"invalid record, no type was specified","source":{"pointer":"/data/type"}}]}`))
 
 		})
 
+		Context("Invalid documents", func() {
+			doRequest := func(payload, url, method string) {
+				req, err := http.NewRequest(method, url, strings.NewReader(payload))
+				Expect(err).To(BeNil())
+				api.Handler().ServeHTTP(rec, req)
+			}
+
+			It("returns 409 for a mismatching type", func() {
+				doRequest(`{"data": {"type": "comments", "attributes": {"title": "New Title"}}}`, "/v1/posts", "POST")
+				Expect(rec.Code).To(Equal(http.StatusConflict))
+				Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{
+					"status": "409",
+					"title": "Type comments does not match the endpoint type posts",
+					"source": {"pointer": "/data/type"}
+				}]}`))
+			})
+
+			It("returns 400 for missing data", func() {
+				doRequest(`{}`, "/v1/posts/1", "PATCH")
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data"}`))
+			})
+
+			It("returns 400 for malformed JSON", func() {
+				doRequest(`{"data": `, "/v1/posts", "POST")
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+			})
+
+			It("points to attributes with a wrong type", func() {
+				doRequest(`{"data": {"type": "posts", "attributes": {"title": 42}}}`, "/v1/posts", "POST")
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{
+					"status": "400",
+					"title": "Invalid number value for attribute title",
+					"source": {"pointer": "/data/attributes/title"}
+				}]}`))
+			})
+
+			It("points to malformed relationship data", func() {
+				doRequest(`{"data": [{"type": "comments", "id": "1"}, {"type": "comments"}]}`, "/v1/posts/1/relationships/comments", "POST")
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{
+					"status": "400",
+					"title": "no id field found inside data object",
+					"source": {"pointer": "/data/1/id"}
+				}]}`))
+
+				rec = httptest.NewRecorder()
+				doRequest(`{"data": {"type": "users"}}`, "/v1/posts/1/relationships/author", "PATCH")
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data/id"}`))
+
+				rec = httptest.NewRecorder()
+				doRequest(`{"meta": {}}`, "/v1/posts/1/relationships/comments", "DELETE")
+				Expect(rec.Code).To(Equal(http.StatusBadRequest))
+				Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data"}`))
+			})
+		})
+
 		Context("Updating", func() {
 			doRequest := func(payload, url, method string) {
 				reqBody := strings.NewReader(payload)
@@ -1580,7 +1647,7 @@ var _ = Describe("RestHandler", func() {
 		})
 
 		It("only returns requested post fields for single post", func() {
-			req, err := http.NewRequest("GET", "/posts/1?fields[posts]=title,value", nil)
+			req, err := http.NewRequest("GET", "/posts/1?fields[posts]=title,value,author", nil)
 			Expect(err).ToNot(HaveOccurred())
 			api.Handler().ServeHTTP(rec, req)
 			Expect(rec.Code).To(Equal(http.StatusOK))
@@ -1602,21 +1669,7 @@ var _ = Describe("RestHandler", func() {
 								"related": "/posts/1/author",
 								"self": "/posts/1/relationships/author"
 								}
-							},
-						"bananas": {
-						"data": [],
-							"links": {
-								"related": "/posts/1/bananas",
-								"self": "/posts/1/relationships/bananas"
-								}
-							},
-						"comments": {
-							"data": [],
-							"links": {
-								"related": "/posts/1/comments",
-								"self": "/posts/1/relationships/comments"
 							}
-						}
 					}
 				},
 				"included": [
@@ -1643,32 +1696,6 @@ var _ = Describe("RestHandler", func() {
 					"type": "posts",
 					"attributes": {
 						"title": "Nice Post"
-					},
-					"relationships": {
-						"author": {
-							"data": {
-								"id": "666",
-								"type": "users"
-							},
-							"links": {
-								"related": "/posts/1/author",
-								"self": "/posts/1/relationships/author"
-								}
-						},
-						"bananas": {
-							"data": [],
-							"links": {
-								"related": "/posts/1/bananas",
-								"self": "/posts/1/relationships/bananas"
-								}
-							},
-						"comments": {
-							"data": [],
-							"links": {
-								"related": "/posts/1/comments",
-								"self": "/posts/1/relationships/comments"
-							}
-						}
 					}
 				},
 				"included": [
@@ -1684,7 +1711,7 @@ var _ = Describe("RestHandler", func() {
 		})
 
 		It("FindAll: only returns requested post field for single post and includes", func() {
-			req, err := http.NewRequest("GET", "/posts?fields[posts]=title&fields[users]=name", nil)
+			req, err := http.NewRequest("GET", "/posts?fields[posts]=title,comments&fields[users]=name", nil)
 			Expect(err).ToNot(HaveOccurred())
 			api.Handler().ServeHTTP(rec, req)
 			Expect(rec.Code).To(Equal(http.StatusOK))
@@ -1696,23 +1723,6 @@ var _ = Describe("RestHandler", func() {
 						"title": "Nice Post"
 					},
 					"relationships": {
-						"author": {
-							"data": {
-								"id": "666",
-								"type": "users"
-							},
-							"links": {
-								"related": "/posts/1/author",
-								"self": "/posts/1/relationships/author"
-								}
-						},
-						"bananas": {
-							"data": [],
-							"links": {
-								"related": "/posts/1/bananas",
-								"self": "/posts/1/relationships/bananas"
-								}
-							},
 						"comments": {
 							"data": [],
 							"links": {
@@ -1734,6 +1744,15 @@ var _ = Describe("RestHandler", func() {
 			}`))
 		})
 
+		It("passes the requested fields to the source", func() {
+			req, err := http.NewRequest("GET", "/posts?fields[posts]=title,value&fields[users]=name", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buildRequest(&APIContext{}, req).Fields).To(Equal(map[string][]string{
+				"posts": {"title", "value"},
+				"users": {"name"},
+			}))
+		})
+
 		It("Summarize all invalid field query parameters as error", func() {
 			req, err := http.NewRequest("GET", "/posts?fields[posts]=title,nonexistent&fields[users]=name,title,fluffy,pink", nil)
 			Expect(err).ToNot(HaveOccurred())
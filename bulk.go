@@ -0,0 +1,332 @@
+package api2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+// BulkErrors contains the errors of single resources of a bulk request by
+// their index in the data array. Bulk sources can return them to report
+// which resources failed, they are converted into one error object per
+// resource with a source pointer to it.
+type BulkErrors map[int]error
+
+// Error returns all errors as one string
+func (e BulkErrors) Error() string {
+	messages := []string{}
+	for _, index := range e.indices() {
+		messages = append(messages, fmt.Sprintf("%d: %s", index, e[index]))
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+func (e BulkErrors) indices() []int {
+	indices := make([]int, 0, len(e))
+	for index := range e {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	return indices
+}
+
+// bulkError converts bulk errors into one HTTPError. Its status is the status
+// of all errors if they match, otherwise 400 Bad Request or 500 Internal
+// Server Error if any of them is a server error. Nil errors are skipped,
+// without any error it is a 500 Internal Server Error.
+func (api *API) bulkError(errs BulkErrors) HTTPError {
+	var (
+		status  int
+		objects []jsonapi.Error
+	)
+	for _, index := range errs.indices() {
+		if errs[index] == nil {
+			continue
+		}
+
+		httpError := pointBulkError(api.httpError(errs[index]), index)
+		switch {
+		case status == 0:
+			status = httpError.status
+		case status >= http.StatusInternalServerError || httpError.status >= http.StatusInternalServerError:
+			status = http.StatusInternalServerError
+		case status != httpError.status:
+			status = http.StatusBadRequest
+		}
+		objects = append(objects, httpError.Errors...)
+	}
+
+	if status == 0 {
+		return NewHTTPError(errs, "Bulk request failed without errors of single resources", http.StatusInternalServerError)
+	}
+
+	httpError := NewHTTPError(errs, http.StatusText(status), status)
+	httpError.Errors = objects
+
+	return httpError
+}
+
+// pointBulkError points all errors of a resource to its index in the data array
+func pointBulkError(httpError HTTPError, index int) HTTPError {
+	prefix := fmt.Sprintf("/data/%d", index)
+
+	if len(httpError.Errors) == 0 {
+		httpError.Errors = []jsonapi.Error{{
+			Title:  httpError.msg,
+			Status: strconv.Itoa(httpError.status),
+		}}
+	}
+
+	errs := make([]jsonapi.Error, len(httpError.Errors))
+	for i, e := range httpError.Errors {
+		source := jsonapi.ErrorSource{Pointer: prefix}
+		if e.Source != nil {
+			source = *e.Source
+			if source.Parameter == "" && (source.Pointer == "/data" || strings.HasPrefix(source.Pointer, "/data/")) {
+				source.Pointer = prefix + strings.TrimPrefix(source.Pointer, "/data")
+			}
+		}
+		e.Source = &source
+		errs[i] = e
+	}
+	httpError.Errors = errs
+
+	return httpError
+}
+
+// bulkItems returns the documents of the single resources of a bulk request,
+// or false if the primary data of body is not an array
+func bulkItems(body []byte) ([][]byte, bool) {
+	document := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, false
+	}
+
+	data := bytes.TrimSpace(document.Data)
+	if len(data) == 0 || data[0] != '[' {
+		return nil, false
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, false
+	}
+
+	documents := make([][]byte, len(items))
+	for i, item := range items {
+		documents[i] = []byte(`{"data":` + string(item) + `}`)
+	}
+
+	return documents, true
+}
+
+// notBulkError is returned for bulk requests to resources without bulk support
+func (res *resource) notBulkError() error {
+	return newSourceError(nil, http.StatusForbidden, fmt.Sprintf("Resource %s does not support bulk requests", res.name), "/data")
+}
+
+func (res *resource) handleBulkCreate(c APIContexter, w http.ResponseWriter, r *http.Request, items [][]byte, info information) error {
+	source, ok := sourceAs[BulkCreator](res.source)
+	if !ok {
+		return res.notBulkError()
+	}
+
+	objs := make([]interface{}, len(items))
+	errs := BulkErrors{}
+	for i, item := range items {
+		if err := res.checkIdentifier(item, ""); err != nil {
+			errs[i] = err
+			continue
+		}
+
+		obj, err := res.unmarshalNew(item)
+		if err != nil {
+			errs[i] = unmarshalError(err)
+			continue
+		}
+
+		if err := validate(obj); err != nil {
+			errs[i] = err
+			continue
+		}
+
+		objs[i] = obj
+	}
+	if len(errs) > 0 {
+		return res.api.bulkError(errs)
+	}
+
+	response, err := source.BulkCreate(objs, buildRequest(c, r))
+	if err != nil {
+		return res.sourceBulkError(err)
+	}
+
+	switch response.StatusCode() {
+	case http.StatusCreated:
+		return res.respondWith(c, response, info, http.StatusCreated, w, r)
+	case http.StatusAccepted, http.StatusNoContent:
+		w.WriteHeader(response.StatusCode())
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for method BulkCreate", response.StatusCode(), res.name)
+	}
+}
+
+func (res *resource) handleBulkUpdate(c APIContexter, w http.ResponseWriter, r *http.Request, info information) error {
+	source, ok := sourceAs[BulkUpdater](res.source)
+	if !ok {
+		return res.notBulkError()
+	}
+
+	body, err := unmarshalRequest(r)
+	if err != nil {
+		return err
+	}
+
+	items, ok := bulkItems(body)
+	if !ok {
+		return newSourceError(nil, http.StatusBadRequest, "Data must be an array of resources for bulk updates", "/data")
+	}
+
+	objs := make([]interface{}, len(items))
+	errs := BulkErrors{}
+	for i, item := range items {
+		obj, err := res.unmarshalBulkUpdate(c, r, source, item)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		objs[i] = obj
+	}
+	if len(errs) > 0 {
+		return res.api.bulkError(errs)
+	}
+
+	response, err := source.BulkUpdate(objs, buildRequest(c, r))
+	if err != nil {
+		return res.sourceBulkError(err)
+	}
+
+	switch response.StatusCode() {
+	case http.StatusOK:
+		if response.Result() == nil {
+			return fmt.Errorf("Expected BulkUpdate to return the updated objects of resource %s", res.name)
+		}
+
+		return res.respondWith(c, response, info, http.StatusOK, w, r)
+	case http.StatusAccepted, http.StatusNoContent:
+		w.WriteHeader(response.StatusCode())
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for method BulkUpdate", response.StatusCode(), res.name)
+	}
+}
+
+// unmarshalBulkUpdate unmarshals one resource of a bulk update into the
+// object returned by FindOne and validates it
+func (res *resource) unmarshalBulkUpdate(c APIContexter, r *http.Request, source BulkUpdater, item []byte) (interface{}, error) {
+	identifier := struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}{}
+	if err := json.Unmarshal(item, &identifier); err != nil || identifier.Data.ID == "" {
+		return nil, newSourceError(nil, http.StatusBadRequest, "Resources of bulk updates need an id", "/data/id")
+	}
+
+	id := identifier.Data.ID
+	if err := res.checkIdentifier(item, id); err != nil {
+		return nil, err
+	}
+
+	existing, err := source.FindOne(id, buildRequest(c, r))
+	if err != nil {
+		return nil, err
+	}
+
+	if existing == nil || existing.Result() == nil {
+		return nil, newSourceError(nil, http.StatusNotFound, fmt.Sprintf("Resource %s with id %s does not exist", res.name, id), "/data/id")
+	}
+
+	obj, err := unmarshalExisting(existing.Result(), item)
+	if err != nil {
+		return nil, unmarshalError(err)
+	}
+
+	if err := validate(obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (res *resource) handleBulkDelete(c APIContexter, w http.ResponseWriter, r *http.Request) error {
+	source, ok := sourceAs[BulkDeleter](res.source)
+	if !ok {
+		return res.notBulkError()
+	}
+
+	body, err := unmarshalRequest(r)
+	if err != nil {
+		return err
+	}
+
+	document := struct {
+		Data interface{} `json:"data"`
+	}{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return NewHTTPError(err, err.Error(), http.StatusBadRequest)
+	}
+
+	ids, err := toManyIDs(document.Data)
+	if err != nil {
+		return err
+	}
+
+	for i, entry := range document.Data.([]interface{}) {
+		if resourceType, _ := entry.(map[string]interface{})["type"].(string); resourceType != "" && resourceType != res.name {
+			return newSourceError(nil, http.StatusConflict, fmt.Sprintf("Type %s does not match the endpoint type %s", resourceType, res.name), fmt.Sprintf("/data/%d/type", i))
+		}
+	}
+
+	response, err := source.BulkDelete(ids, buildRequest(c, r))
+	if err != nil {
+		return res.sourceBulkError(err)
+	}
+
+	switch response.StatusCode() {
+	case http.StatusOK:
+		data := map[string]interface{}{
+			"meta": response.Metadata(),
+		}
+
+		return res.marshalResponse(data, "", w, http.StatusOK, withAppliedProfiles(r, response))
+	case http.StatusAccepted, http.StatusNoContent:
+		w.WriteHeader(response.StatusCode())
+		return nil
+	default:
+		return fmt.Errorf("invalid status code %d from resource %s for method BulkDelete", response.StatusCode(), res.name)
+	}
+}
+
+// sourceBulkError converts BulkErrors returned by a bulk source
+func (res *resource) sourceBulkError(err error) error {
+	var errs BulkErrors
+	if errors.As(err, &errs) {
+		return res.api.bulkError(errs)
+	}
+
+	return err
+}
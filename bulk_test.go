@@ -0,0 +1,197 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type bulkAccountSource struct {
+	*accountSource
+	deleted []string
+	err     error
+}
+
+func (s *bulkAccountSource) BulkCreate(objs []interface{}, req Request) (Responder, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	accounts := []Account{}
+	for _, obj := range objs {
+		account := obj.(Account)
+		account.ID = account.Email
+		s.accounts[account.ID] = account
+		accounts = append(accounts, account)
+	}
+	return &Response{Res: accounts, Code: http.StatusCreated}, nil
+}
+
+func (s *bulkAccountSource) BulkUpdate(objs []interface{}, req Request) (Responder, error) {
+	for _, obj := range objs {
+		account := obj.(Account)
+		s.accounts[account.ID] = account
+	}
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *bulkAccountSource) BulkDelete(ids []string, req Request) (Responder, error) {
+	s.deleted = append(s.deleted, ids...)
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *bulkAccountSource) FindOne(ID string, req Request) (Responder, error) {
+	account, ok := s.accounts[ID]
+	if !ok {
+		return nil, NewHTTPError(nil, "account not found", http.StatusNotFound)
+	}
+	return &Response{Res: account}, nil
+}
+
+var _ = Describe("Bulk requests", func() {
+	var (
+		api    *API
+		source *bulkAccountSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &bulkAccountSource{accountSource: &accountSource{accounts: map[string]Account{
+			"1": {ID: "1", Email: "jane@example.com", Name: "Jane"},
+			"2": {ID: "2", Email: "joe@example.com", Name: "Joe"},
+		}}}
+		api = NewAPI("v1")
+		api.AddResource(Account{}, source)
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+		rec = httptest.NewRecorder()
+	})
+
+	request := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		rec = httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("creates multiple resources", func() {
+		request("POST", "/v1/accounts", `{"data": [
+			{"type": "accounts", "attributes": {"email": "ann@example.com", "name": "Ann"}},
+			{"type": "accounts", "attributes": {"email": "bob@example.com", "name": "Bob"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Body.String()).To(MatchJSON(`{"data": [
+			{"type": "accounts", "id": "ann@example.com", "attributes": {"email": "ann@example.com", "name": "Ann", "age": null, "tags": null, "bio": ""}},
+			{"type": "accounts", "id": "bob@example.com", "attributes": {"email": "bob@example.com", "name": "Bob", "age": null, "tags": null, "bio": ""}}
+		]}`))
+		Expect(source.accounts).To(HaveKey("bob@example.com"))
+	})
+
+	It("reports the errors of all invalid resources", func() {
+		request("POST", "/v1/accounts", `{"data": [
+			{"type": "accounts", "attributes": {"email": "ann@example.com", "name": "Ann"}},
+			{"type": "accounts", "attributes": {"email": "bob", "name": "Bob"}},
+			{"type": "accounts", "attributes": {"email": "cid@example.com"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [
+			{
+				"status": "422",
+				"code": "API2GO_INVALID_ATTRIBUTE",
+				"title": "Invalid attribute",
+				"detail": "email must be a valid email address",
+				"source": {"pointer": "/data/1/attributes/email"}
+			},
+			{
+				"status": "422",
+				"code": "API2GO_INVALID_ATTRIBUTE",
+				"title": "Invalid attribute",
+				"detail": "name is required",
+				"source": {"pointer": "/data/2/attributes/name"}
+			}
+		]}`))
+		Expect(source.accounts).ToNot(HaveKey("ann@example.com"))
+
+		request("POST", "/v1/accounts", `{"data": [
+			{"type": "posts", "attributes": {"email": "ann@example.com", "name": "Ann"}},
+			{"type": "accounts", "attributes": {"email": "bob", "name": "Bob"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data/0/type"}`))
+	})
+
+	It("points errors of the source to the failed resources", func() {
+		source.err = BulkErrors{1: NewHTTPError(nil, "email is taken", http.StatusConflict)}
+		request("POST", "/v1/accounts", `{"data": [
+			{"type": "accounts", "attributes": {"email": "ann@example.com", "name": "Ann"}},
+			{"type": "accounts", "attributes": {"email": "jane@example.com", "name": "Jane"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [
+			{"status": "409", "title": "email is taken", "source": {"pointer": "/data/1"}}
+		]}`))
+	})
+
+	It("handles wrapped, empty and nil errors of the source", func() {
+		body := `{"data": [
+			{"type": "accounts", "attributes": {"email": "ann@example.com", "name": "Ann"}},
+			{"type": "accounts", "attributes": {"email": "jane@example.com", "name": "Jane"}}
+		]}`
+
+		source.err = fmt.Errorf("creating accounts: %w", BulkErrors{0: nil, 1: NewHTTPError(nil, "email is taken", http.StatusConflict)})
+		request("POST", "/v1/accounts", body)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [
+			{"status": "409", "title": "email is taken", "source": {"pointer": "/data/1"}}
+		]}`))
+
+		source.err = BulkErrors{}
+		request("POST", "/v1/accounts", body)
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rec.Body.String()).To(ContainSubstring("Bulk request failed without errors of single resources"))
+	})
+
+	It("updates multiple resources", func() {
+		request("PATCH", "/v1/accounts", `{"data": [
+			{"type": "accounts", "id": "1", "attributes": {"role": "admin"}},
+			{"type": "accounts", "id": "2", "attributes": {"name": "Joseph"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.accounts["1"]).To(Equal(Account{ID: "1", Email: "jane@example.com", Name: "Jane", Role: "admin"}))
+		Expect(source.accounts["2"].Name).To(Equal("Joseph"))
+
+		request("PATCH", "/v1/accounts", `{"data": [
+			{"type": "accounts", "attributes": {"role": "admin"}},
+			{"type": "accounts", "id": "3", "attributes": {"name": "Joseph"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [
+			{"status": "400", "title": "Resources of bulk updates need an id", "source": {"pointer": "/data/0/id"}},
+			{"status": "404", "title": "account not found", "source": {"pointer": "/data/1"}}
+		]}`))
+	})
+
+	It("deletes multiple resources", func() {
+		request("DELETE", "/v1/accounts", `{"data": [{"type": "accounts", "id": "1"}, {"type": "accounts", "id": "2"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.deleted).To(Equal([]string{"1", "2"}))
+
+		request("DELETE", "/v1/accounts", `{"data": [{"type": "posts", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data/0/type"}`))
+	})
+
+	It("allows bulk methods on the collection", func() {
+		request("OPTIONS", "/v1/accounts", "")
+		Expect(rec.Header().Get("Allow")).To(Equal("OPTIONS,GET,PATCH,DELETE,POST"))
+	})
+
+	It("rejects bulk requests to resources without bulk support", func() {
+		request("POST", "/v1/posts", `{"data": [{"type": "posts", "attributes": {"title": "New"}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(rec.Body.String()).To(ContainSubstring(`"title":"Resource posts does not support bulk requests"`))
+	})
+})
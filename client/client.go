@@ -0,0 +1,267 @@
+// Package client calls the routes of a remote api2go API. Requests and
+// responses are marshalled with the jsonapi package, so the same structs that
+// are used by the server resources can be used by the client.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/jtumidanski/api2go"
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+const defaultContentType = "application/vnd.api+json"
+
+// Client calls the routes of an api2go API
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	// ContentType is sent in the Content-Type and Accept headers of all requests
+	ContentType string
+}
+
+// New returns a client for the api at baseURL which must contain the prefix
+// of the api, e.g. http://localhost:31415/v1. If httpClient is nil,
+// http.DefaultClient is used.
+func New(baseURL string, httpClient *http.Client) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL:     parsed,
+		httpClient:  httpClient,
+		ContentType: defaultContentType,
+	}, nil
+}
+
+// FindOne fetches the resource name with the given id into target, which must
+// be a pointer to a struct implementing jsonapi.UnmarshalIdentifier. Included
+// resources are passed to SetReferencedStructs if target implements
+// jsonapi.UnmarshalIncludedRelations.
+func (c *Client) FindOne(ctx context.Context, name, id string, target interface{}, query url.Values) error {
+	_, err := c.fetch(ctx, c.url(query, name, id), target)
+	return err
+}
+
+// FindAll fetches all resources of name into target, which must be a pointer
+// to a slice. The next links of paginated responses are followed until the
+// last page was fetched.
+func (c *Client) FindAll(ctx context.Context, name string, target interface{}, query url.Values) error {
+	return c.fetchAll(ctx, c.url(query, name), target)
+}
+
+// FindRelated fetches the related resources of a relationship into target. It
+// behaves like FindOne for to-one and like FindAll for to-many
+// relationships. target is left untouched if a to-one relationship is empty.
+func (c *Client) FindRelated(ctx context.Context, name, id, relationship string, target interface{}, query url.Values) error {
+	return c.fetchAll(ctx, c.url(query, name, id, relationship), target)
+}
+
+// FindRelationship returns the resource linkage of a relationship. DataObject
+// is set for to-one, DataArray for to-many relationships.
+func (c *Client) FindRelationship(ctx context.Context, name, id, relationship string) (*jsonapi.RelationshipDataContainer, error) {
+	_, body, err := c.do(ctx, http.MethodGet, c.url(nil, name, id, "relationships", relationship), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var document relationshipDocument
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+
+	return document.Data, nil
+}
+
+// Create sends obj to the api and unmarshals the created resource back into
+// obj, which must therefore be a pointer.
+func (c *Client) Create(ctx context.Context, obj jsonapi.MarshalIdentifier) error {
+	return c.send(ctx, http.MethodPost, c.url(nil, resourceName(obj)), obj)
+}
+
+// Update sends obj to the api and unmarshals the updated resource back into
+// obj, which must therefore be a pointer.
+func (c *Client) Update(ctx context.Context, obj jsonapi.MarshalIdentifier) error {
+	return c.send(ctx, http.MethodPatch, c.url(nil, resourceName(obj), obj.GetID()), obj)
+}
+
+// Delete deletes the resource name with the given id
+func (c *Client) Delete(ctx context.Context, name, id string) error {
+	_, _, err := c.do(ctx, http.MethodDelete, c.url(nil, name, id), nil)
+	return err
+}
+
+// ReplaceRelationship replaces the whole relationship. Set DataObject of data
+// for to-one and DataArray for to-many relationships, a nil data clears a
+// to-one relationship.
+func (c *Client) ReplaceRelationship(ctx context.Context, name, id, relationship string, data *jsonapi.RelationshipDataContainer) error {
+	return c.sendRelationship(ctx, http.MethodPatch, name, id, relationship, data)
+}
+
+// AddToManyRelationship adds the given references to a to-many relationship
+func (c *Client) AddToManyRelationship(ctx context.Context, name, id, relationship string, references []jsonapi.RelationshipData) error {
+	return c.sendRelationship(ctx, http.MethodPost, name, id, relationship, &jsonapi.RelationshipDataContainer{DataArray: references})
+}
+
+// DeleteToManyRelationship removes the given references from a to-many relationship
+func (c *Client) DeleteToManyRelationship(ctx context.Context, name, id, relationship string, references []jsonapi.RelationshipData) error {
+	return c.sendRelationship(ctx, http.MethodDelete, name, id, relationship, &jsonapi.RelationshipDataContainer{DataArray: references})
+}
+
+type relationshipDocument struct {
+	Data *jsonapi.RelationshipDataContainer `json:"data"`
+}
+
+func (c *Client) sendRelationship(ctx context.Context, method, name, id, relationship string, data *jsonapi.RelationshipDataContainer) error {
+	body, err := json.Marshal(relationshipDocument{Data: data})
+	if err != nil {
+		return err
+	}
+
+	_, _, err = c.do(ctx, method, c.url(nil, name, id, "relationships", relationship), body)
+	return err
+}
+
+func (c *Client) send(ctx context.Context, method, target string, obj jsonapi.MarshalIdentifier) error {
+	payload, err := jsonapi.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	status, body, err := c.do(ctx, method, target, payload)
+	if err != nil {
+		return err
+	}
+
+	// 202 and 204 responses have no body, the resource was accepted as it is
+	if status == http.StatusAccepted || status == http.StatusNoContent || len(body) == 0 {
+		return nil
+	}
+
+	return jsonapi.Unmarshal(body, obj)
+}
+
+// fetchAll fetches target and follows all next links. Each page is
+// unmarshalled into the same target, so slices are appended.
+func (c *Client) fetchAll(ctx context.Context, target string, into interface{}) error {
+	visited := map[string]bool{}
+
+	for target != "" && !visited[target] {
+		visited[target] = true
+
+		links, err := c.fetch(ctx, target, into)
+		if err != nil {
+			return err
+		}
+
+		target = ""
+		if next, ok := links["next"]; ok && next.Href != "" {
+			resolved, err := c.baseURL.Parse(next.Href)
+			if err != nil {
+				return err
+			}
+			target = resolved.String()
+		}
+	}
+
+	return nil
+}
+
+// fetch gets target, unmarshals the data into into and returns the links of
+// the document.
+func (c *Client) fetch(ctx context.Context, target string, into interface{}) (jsonapi.Links, error) {
+	_, body, err := c.do(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	document := jsonapi.Document{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return nil, err
+	}
+
+	// an empty to-one relationship
+	if document.Data == nil || (document.Data.DataObject == nil && document.Data.DataArray == nil) {
+		return document.Links, nil
+	}
+
+	return document.Links, jsonapi.Unmarshal(body, into)
+}
+
+// do sends a request and returns the status and body of a successful
+// response. Responses with an error status are returned as api2go.HTTPError.
+func (c *Client) do(ctx context.Context, method, target string, payload []byte) (int, []byte, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req.Header.Set("Accept", c.ContentType)
+	if payload != nil {
+		req.Header.Set("Content-Type", c.ContentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.StatusCode, nil, newHTTPError(resp.StatusCode, respBody)
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, nil, fmt.Errorf("unexpected status code %d for %s %s", resp.StatusCode, method, target)
+	}
+
+	return resp.StatusCode, respBody, nil
+}
+
+// url builds the url of the given path segments relative to the base url
+func (c *Client) url(query url.Values, segments ...string) string {
+	result := c.baseURL.JoinPath(segments...)
+	if len(query) > 0 {
+		result.RawQuery = query.Encode()
+	}
+
+	return result.String()
+}
+
+// newHTTPError decodes the errors of a response body into an api2go.HTTPError
+func newHTTPError(status int, body []byte) api2go.HTTPError {
+	httpError := api2go.NewHTTPError(nil, http.StatusText(status), status)
+	if err := json.Unmarshal(body, &httpError); err != nil {
+		httpError.Errors = nil
+	}
+
+	return httpError
+}
+
+// resourceName returns the jsonapi type of obj, which is also the name of its
+// route.
+func resourceName(obj interface{}) string {
+	return jsonapi.EntityName(obj)
+}
@@ -0,0 +1,271 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/jtumidanski/api2go"
+	"github.com/jtumidanski/api2go/client"
+	"github.com/jtumidanski/api2go/jsonapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type Post struct {
+	ID         string    `json:"-"`
+	Title      string    `json:"title"`
+	CommentIDs []string  `json:"-"`
+	Comments   []Comment `json:"-"`
+}
+
+func (p Post) GetID() string {
+	return p.ID
+}
+
+func (p *Post) SetID(id string) error {
+	p.ID = id
+	return nil
+}
+
+func (p Post) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{{Name: "comments", Type: "comments"}}
+}
+
+func (p Post) GetReferencedIDs() []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{}
+	for _, id := range p.CommentIDs {
+		result = append(result, jsonapi.ReferenceID{ID: id, Name: "comments", Type: "comments"})
+	}
+	return result
+}
+
+func (p *Post) SetToManyReferenceIDs(name string, IDs []string) error {
+	p.CommentIDs = IDs
+	return nil
+}
+
+func (p *Post) AddToManyIDs(name string, IDs []string) error {
+	p.CommentIDs = append(p.CommentIDs, IDs...)
+	return nil
+}
+
+func (p *Post) DeleteToManyIDs(name string, IDs []string) error {
+	kept := []string{}
+	for _, existing := range p.CommentIDs {
+		obsolete := false
+		for _, id := range IDs {
+			obsolete = obsolete || existing == id
+		}
+		if !obsolete {
+			kept = append(kept, existing)
+		}
+	}
+	p.CommentIDs = kept
+	return nil
+}
+
+func (p *Post) SetReferencedStructs(references map[string]map[string]jsonapi.Data) error {
+	p.Comments = nil
+	for _, id := range p.CommentIDs {
+		if data, ok := references["comments"][id]; ok {
+			comment := Comment{ID: id}
+			if err := jsonapi.ProcessIncludeData(&comment, data, references); err != nil {
+				return err
+			}
+			p.Comments = append(p.Comments, comment)
+		}
+	}
+	return nil
+}
+
+type Comment struct {
+	ID   string `json:"-"`
+	Text string `json:"text"`
+}
+
+func (c Comment) GetID() string {
+	return c.ID
+}
+
+func (c *Comment) SetID(id string) error {
+	c.ID = id
+	return nil
+}
+
+type postSource struct {
+	posts map[string]Post
+}
+
+func (s *postSource) sorted() []Post {
+	result := []Post{}
+	for _, post := range s.posts {
+		result = append(result, post)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+func (s *postSource) FindAll(req api2go.Request) (api2go.Responder, error) {
+	return &api2go.Response{Res: s.sorted()}, nil
+}
+
+func (s *postSource) PaginatedFindAll(req api2go.Request) (uint, api2go.Responder, error) {
+	posts := s.sorted()
+	number, _ := strconv.Atoi(req.Pagination["number"])
+	size, _ := strconv.Atoi(req.Pagination["size"])
+	start := (number - 1) * size
+	end := start + size
+	if end > len(posts) {
+		end = len(posts)
+	}
+	return uint(len(posts)), &api2go.Response{Res: posts[start:end]}, nil
+}
+
+func (s *postSource) FindOne(ID string, req api2go.Request) (api2go.Responder, error) {
+	post, ok := s.posts[ID]
+	if !ok {
+		return nil, api2go.NewHTTPError(errors.New("not found"), "post not found", http.StatusNotFound)
+	}
+	return &api2go.Response{Res: post}, nil
+}
+
+func (s *postSource) Create(obj interface{}, req api2go.Request) (api2go.Responder, error) {
+	post := obj.(Post)
+	post.ID = strconv.Itoa(len(s.posts) + 1)
+	s.posts[post.ID] = post
+	return &api2go.Response{Res: post, Code: http.StatusCreated}, nil
+}
+
+func (s *postSource) Update(obj interface{}, req api2go.Request) (api2go.Responder, error) {
+	post := obj.(Post)
+	post.Title += " (edited)"
+	s.posts[post.ID] = post
+	return &api2go.Response{Res: post, Code: http.StatusOK}, nil
+}
+
+func (s *postSource) Delete(ID string, req api2go.Request) (api2go.Responder, error) {
+	delete(s.posts, ID)
+	return &api2go.Response{Code: http.StatusNoContent}, nil
+}
+
+type commentSource struct {
+	comments map[string]Comment
+	posts    *postSource
+}
+
+func (s *commentSource) FindAll(req api2go.Request) (api2go.Responder, error) {
+	result := []Comment{}
+	if postIDs, ok := req.QueryParams["postsID"]; ok {
+		for _, id := range s.posts.posts[postIDs[0]].CommentIDs {
+			result = append(result, s.comments[id])
+		}
+	}
+	return &api2go.Response{Res: result}, nil
+}
+
+func (s *commentSource) FindOne(ID string, req api2go.Request) (api2go.Responder, error) {
+	comment, ok := s.comments[ID]
+	if !ok {
+		return nil, api2go.NewHTTPError(errors.New("not found"), "comment not found", http.StatusNotFound)
+	}
+	return &api2go.Response{Res: comment}, nil
+}
+
+var _ = Describe("Client", func() {
+	var (
+		server *httptest.Server
+		posts  *postSource
+		c      *client.Client
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		posts = &postSource{posts: map[string]Post{
+			"1": {ID: "1", Title: "First", CommentIDs: []string{"1", "2"}},
+			"2": {ID: "2", Title: "Second", CommentIDs: []string{}},
+			"3": {ID: "3", Title: "Third", CommentIDs: []string{}},
+		}}
+		comments := &commentSource{posts: posts, comments: map[string]Comment{
+			"1": {ID: "1", Text: "Nice"},
+			"2": {ID: "2", Text: "Boring"},
+			"3": {ID: "3", Text: "Meh"},
+		}}
+
+		api := api2go.NewAPI("v1")
+		api.AddResource(Post{}, posts)
+		api.AddResource(Comment{}, comments)
+		server = httptest.NewServer(api.Handler())
+
+		var err error
+		c, err = client.New(server.URL+"/v1", server.Client())
+		Expect(err).ToNot(HaveOccurred())
+		ctx = context.Background()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("finds one resource and hydrates included structs", func() {
+		var post Post
+		err := c.FindOne(ctx, "posts", "1", &post, url.Values{"include": {"comments"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(post).To(Equal(Post{
+			ID:         "1",
+			Title:      "First",
+			CommentIDs: []string{"1", "2"},
+			Comments:   []Comment{{ID: "1", Text: "Nice"}, {ID: "2", Text: "Boring"}},
+		}))
+	})
+
+	It("follows pagination links", func() {
+		var result []Post
+		err := c.FindAll(ctx, "posts", &result, url.Values{"page[number]": {"1"}, "page[size]": {"2"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(HaveLen(3))
+		Expect(result[2].Title).To(Equal("Third"))
+	})
+
+	It("decodes errors into an HTTPError", func() {
+		var post Post
+		err := c.FindOne(ctx, "posts", "42", &post, nil)
+		var httpError api2go.HTTPError
+		Expect(errors.As(err, &httpError)).To(BeTrue())
+		Expect(httpError.Status()).To(Equal(http.StatusNotFound))
+		Expect(httpError.Errors).To(HaveLen(1))
+		Expect(httpError.Errors[0].Title).To(Equal("post not found"))
+	})
+
+	It("creates, updates and deletes resources", func() {
+		post := &Post{Title: "New", CommentIDs: []string{}}
+		Expect(c.Create(ctx, post)).To(Succeed())
+		Expect(post.ID).To(Equal("4"))
+
+		Expect(c.Update(ctx, post)).To(Succeed())
+		Expect(post.Title).To(Equal("New (edited)"))
+
+		Expect(c.Delete(ctx, "posts", "4")).To(Succeed())
+		Expect(posts.posts).ToNot(HaveKey("4"))
+	})
+
+	It("reads and edits relationships", func() {
+		var related []Comment
+		Expect(c.FindRelated(ctx, "posts", "1", "comments", &related, nil)).To(Succeed())
+		Expect(related).To(Equal([]Comment{{ID: "1", Text: "Nice"}, {ID: "2", Text: "Boring"}}))
+
+		Expect(c.AddToManyRelationship(ctx, "posts", "1", "comments", []jsonapi.RelationshipData{{Type: "comments", ID: "3"}})).To(Succeed())
+		Expect(c.DeleteToManyRelationship(ctx, "posts", "1", "comments", []jsonapi.RelationshipData{{Type: "comments", ID: "1"}})).To(Succeed())
+
+		linkage, err := c.FindRelationship(ctx, "posts", "1", "comments")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(linkage.DataArray).To(Equal([]jsonapi.RelationshipData{{Type: "comments", ID: "2"}, {Type: "comments", ID: "3"}}))
+
+		Expect(c.ReplaceRelationship(ctx, "posts", "1", "comments", &jsonapi.RelationshipDataContainer{DataArray: []jsonapi.RelationshipData{}})).To(Succeed())
+		Expect(posts.posts["1"].CommentIDs).To(BeEmpty())
+	})
+})
@@ -0,0 +1,87 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type clientIDSource struct {
+	*fixtureSource
+	mode ClientIDMode
+}
+
+func (s clientIDSource) ClientIDs() ClientIDMode {
+	return s.mode
+}
+
+var _ = Describe("Client-generated ids", func() {
+	var (
+		api    *API
+		source *clientIDSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &clientIDSource{fixtureSource: &fixtureSource{map[string]*Post{}, false}}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		api.EnableAtomicOperations()
+		rec = httptest.NewRecorder()
+	})
+
+	post := func(url, body string) {
+		req, err := http.NewRequest("POST", url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("allows client ids by default", func() {
+		post("/v1/posts", `{"data": {"type": "posts", "id": "abc", "attributes": {"title": "New"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("forbids client ids", func() {
+		source.mode = ClientIDsForbidden
+		post("/v1/posts", `{"data": {"type": "posts", "id": "abc", "attributes": {"title": "New"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{
+			"status": "403",
+			"title": "Resource posts does not support client-generated ids",
+			"source": {"pointer": "/data/id"}
+		}]}`))
+		Expect(source.posts).To(BeEmpty())
+
+		rec = httptest.NewRecorder()
+		post("/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+	})
+
+	It("requires client ids", func() {
+		source.mode = ClientIDsRequired
+		post("/v1/posts", `{"data": {"type": "posts", "attributes": {"title": "New"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(rec.Body.String()).To(ContainSubstring(`"title":"Resource posts requires client-generated ids"`))
+	})
+
+	It("applies the policy to atomic operations", func() {
+		source.mode = ClientIDsForbidden
+		post("/v1/operations", `{"atomic:operations": [{
+			"op": "add",
+			"data": {"type": "posts", "id": "abc", "attributes": {"title": "New"}}
+		}]}`)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/atomic:operations/0/data/id"}`))
+	})
+
+	It("rejects payloads with the type of another endpoint", func() {
+		req, err := http.NewRequest("PATCH", "/v1/posts/1", strings.NewReader(`{"data": {"type": "comments", "id": "1"}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/data/type"}`))
+	})
+})
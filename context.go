@@ -16,9 +16,23 @@ type APIContexter interface {
 	Reset()
 }
 
-// APIContext api2go context for handlers, nil implementations related to Deadline and Done.
+// The RequestContextSetter interface can be implemented by an APIContexter to
+// wrap the context of the incoming http.Request. It is called for every request
+// before any middleware runs.
+type RequestContextSetter interface {
+	SetRequestContext(ctx context.Context)
+}
+
+// APIContext api2go context for handlers. Deadline, cancellation and values
+// are taken from the wrapped request context.
 type APIContext struct {
-	keys map[string]interface{}
+	keys   map[string]interface{}
+	parent context.Context
+}
+
+// SetRequestContext wraps the context of the incoming request
+func (c *APIContext) SetRequestContext(ctx context.Context) {
+	c.parent = ctx
 }
 
 // Set a string key value in the context
@@ -40,28 +54,43 @@ func (c *APIContext) Get(key string) (value interface{}, exists bool) {
 // Reset resets all values on Context, making it safe to reuse
 func (c *APIContext) Reset() {
 	c.keys = nil
+	c.parent = nil
 }
 
 // Deadline implements net/context
 func (c *APIContext) Deadline() (deadline time.Time, ok bool) {
+	if c.parent != nil {
+		return c.parent.Deadline()
+	}
 	return
 }
 
 // Done implements net/context
 func (c *APIContext) Done() <-chan struct{} {
+	if c.parent != nil {
+		return c.parent.Done()
+	}
 	return nil
 }
 
 // Err implements net/context
 func (c *APIContext) Err() error {
+	if c.parent != nil {
+		return c.parent.Err()
+	}
 	return nil
 }
 
-// Value implements net/context
+// Value implements net/context. Values that were Set take precedence over the
+// values of the request context.
 func (c *APIContext) Value(key interface{}) interface{} {
 	if keyAsString, ok := key.(string); ok {
-		val, _ := c.Get(keyAsString)
-		return val
+		if val, exists := c.Get(keyAsString); exists {
+			return val
+		}
+	}
+	if c.parent != nil {
+		return c.parent.Value(key)
 	}
 	return nil
 }
@@ -1,6 +1,9 @@
 package api2go
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -81,6 +84,49 @@ var _ = Describe("Context", func() {
 
 	})
 
+	Context("Request context", func() {
+		type requestKey struct{}
+
+		It("wraps deadline, cancellation and values", func() {
+			deadline := time.Now().Add(time.Minute)
+			parent, cancel := context.WithDeadline(context.WithValue(context.Background(), requestKey{}, "trace"), deadline)
+			c.SetRequestContext(parent)
+			c.Set("foo", "bar")
+
+			actual, ok := c.Deadline()
+			Expect(ok).To(BeTrue())
+			Expect(actual).To(Equal(deadline))
+			Expect(c.Value(requestKey{})).To(Equal("trace"))
+			Expect(c.Value("foo")).To(Equal("bar"))
+			Expect(c.Err()).ToNot(HaveOccurred())
+
+			cancel()
+			Eventually(c.Done()).Should(BeClosed())
+			Expect(c.Err()).To(Equal(context.Canceled))
+		})
+
+		It("removes the request context on reset", func() {
+			c.SetRequestContext(context.WithValue(context.Background(), requestKey{}, "trace"))
+			c.Reset()
+			Expect(c.Value(requestKey{})).To(BeNil())
+		})
+
+		It("is wrapped by the api for every request", func() {
+			var value interface{}
+			api := NewAPI("v1")
+			api.UseMiddleware(func(c APIContexter, w http.ResponseWriter, r *http.Request) {
+				value = c.Value(requestKey{})
+			})
+			api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+
+			req, err := http.NewRequest("GET", "/v1/posts", nil)
+			Expect(err).ToNot(HaveOccurred())
+			req = req.WithContext(context.WithValue(req.Context(), requestKey{}, "trace"))
+			api.Handler().ServeHTTP(httptest.NewRecorder(), req)
+			Expect(value).To(Equal("trace"))
+		})
+	})
+
 	Context("ContextQueryParams", func() {
 		It("returns them if set", func() {
 			queryParams := map[string][]string{
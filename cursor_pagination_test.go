@@ -0,0 +1,167 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type cursorSource struct {
+	fixtureSource
+	called bool
+}
+
+// ids are used as cursors, the page size is fixed to 2
+func (s *cursorSource) CursorPaginatedFindAll(req Request) (CursorPage, Responder, error) {
+	s.called = true
+	ids := []string{"1", "2", "3", "4", "5"}
+
+	start := 0
+	if after, ok := req.Pagination["after"]; ok {
+		for i, id := range ids {
+			if id == after {
+				start = i + 1
+			}
+		}
+	}
+	if before, ok := req.Pagination["before"]; ok {
+		for i, id := range ids {
+			if id == before {
+				start = i - 2
+			}
+		}
+	}
+
+	end := start + 2
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	posts := []Post{}
+	for _, id := range ids[start:end] {
+		posts = append(posts, *s.posts[id])
+	}
+
+	page := CursorPage{}
+	if end < len(ids) {
+		page.Next = ids[end-1]
+	}
+	if start > 0 {
+		page.Prev = ids[start]
+	}
+
+	return page, &Response{Res: posts}, nil
+}
+
+// cursorOnlySource implements CursorPaginatedFindAll but not FindAll
+type cursorOnlySource struct {
+	source *cursorSource
+}
+
+func (s cursorOnlySource) CursorPaginatedFindAll(req Request) (CursorPage, Responder, error) {
+	return s.source.CursorPaginatedFindAll(req)
+}
+
+var _ = Describe("Cursor pagination", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *cursorSource
+	)
+
+	BeforeEach(func() {
+		source = &cursorSource{fixtureSource: fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+			"2": {ID: "2", Title: "Hello, World!"},
+			"3": {ID: "3", Title: "Hello, World!"},
+			"4": {ID: "4", Title: "Hello, World!"},
+			"5": {ID: "5", Title: "Hello, World!"},
+		}, false}}
+
+		api = NewAPIWithRouting(testPrefix, NewStaticResolver(""), newTestRouter())
+		api.AddResource(Post{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	type cursorResponse struct {
+		Links map[string]string      `json:"links"`
+		Meta  map[string]interface{} `json:"meta"`
+		Data  []map[string]interface{}
+	}
+
+	doRequest := func(URL string) cursorResponse {
+		req, err := http.NewRequest("GET", URL, nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		var response cursorResponse
+		Expect(json.Unmarshal(rec.Body.Bytes(), &response)).To(Succeed())
+		return response
+	}
+
+	It("returns the first page with a next link", func() {
+		response := doRequest("/v1/posts?page[size]=2")
+		Expect(response.Data).To(HaveLen(2))
+		Expect(response.Links).To(Equal(map[string]string{
+			"next": "/v1/posts?page[after]=2&page[size]=2",
+		}))
+		Expect(response.Meta).To(Equal(map[string]interface{}{
+			"page": map[string]interface{}{
+				"hasNext":    true,
+				"hasPrev":    false,
+				"nextCursor": "2",
+				"size":       float64(2),
+			},
+		}))
+	})
+
+	It("returns first, prev and next links in the middle", func() {
+		response := doRequest("/v1/posts?page[size]=2&page[after]=2")
+		Expect(response.Data).To(HaveLen(2))
+		Expect(response.Data[0]["id"]).To(Equal("3"))
+		Expect(response.Links).To(Equal(map[string]string{
+			"first": "/v1/posts?page[size]=2",
+			"prev":  "/v1/posts?page[before]=3&page[size]=2",
+			"next":  "/v1/posts?page[after]=4&page[size]=2",
+		}))
+	})
+
+	It("does not return a next link on the last page", func() {
+		response := doRequest("/v1/posts?page[after]=4")
+		Expect(response.Data).To(HaveLen(1))
+		Expect(response.Links).ToNot(HaveKey("next"))
+		Expect(response.Meta["page"]).To(HaveKeyWithValue("hasNext", false))
+	})
+
+	It("uses number based pagination if requested", func() {
+		doRequest("/v1/posts?page[number]=1&page[size]=2")
+		Expect(source.called).To(BeFalse())
+	})
+	It("serves the first page of sources without FindAll", func() {
+		api = NewAPIWithRouting(testPrefix, NewStaticResolver(""), newTestRouter())
+		api.AddResource(Post{}, cursorOnlySource{source})
+
+		response := doRequest("/v1/posts")
+		Expect(source.called).To(BeTrue())
+		Expect(response.Data).To(HaveLen(2))
+		Expect(response.Data[0]["id"]).To(Equal("1"))
+		Expect(response.Meta["page"]).To(HaveKeyWithValue("hasNext", true))
+	})
+
+	It("rejects page[after] combined with page[before]", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?page[after]=2&page[before]=4", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(source.called).To(BeFalse())
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{
+			"status": "400",
+			"title": "Invalid pagination query parameters",
+			"detail": "page[after] and page[before] can not be combined",
+			"source": {"parameter": "page[before]"}
+		}]}`))
+	})
+})
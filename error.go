@@ -1,9 +1,11 @@
 package api2go
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/jtumidanski/api2go/jsonapi"
+	"net/http"
 	"strconv"
 )
 
@@ -36,6 +38,35 @@ func (e HTTPError) Error() string {
 	return msg
 }
 
+// Unwrap returns the internal error, so it can be inspected with errors.Is and errors.As
+func (e HTTPError) Unwrap() error {
+	return e.err
+}
+
+// Logger is used by the default error handler, *slog.Logger implements it
+type Logger interface {
+	ErrorContext(ctx context.Context, msg string, args ...any)
+}
+
+// ErrorInfo describes an error returned by an api2go route
+type ErrorInfo struct {
+	// Resource is the name of the resource, empty for routes like /operations
+	Resource string
+	// Action is the action of the route, empty for routes without resource
+	Action Action
+	// Err is the returned error, the internal error of an HTTPError can be
+	// retrieved with errors.Unwrap
+	Err error
+}
+
+// ErrorHandlerFunc is called for every error returned by an api2go route
+// before it is written to the client
+type ErrorHandlerFunc func(*http.Request, ErrorInfo)
+
+// ErrorMapperFunc converts errors that are no HTTPError to the HTTPError that
+// is sent to the client
+type ErrorMapperFunc func(error) HTTPError
+
 // marshalHTTPError marshals an internal httpError
 func marshalHTTPError(input HTTPError) string {
 	if len(input.Errors) == 0 {
@@ -1,11 +1,15 @@
 package api2go
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"github.com/jtumidanski/api2go/jsonapi"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"log/slog"
 	"net/http"
+	"net/http/httptest"
 )
 
 type ErrorMarshaler struct{}
@@ -21,6 +25,16 @@ func (e ErrorMarshaler) MarshalError(error) string {
 	return ""
 }
 
+type failingSource struct {
+	err error
+}
+
+func (s failingSource) FindOne(ID string, req Request) (Responder, error) {
+	return nil, s.err
+}
+
+var errNotFound = errors.New("record not found")
+
 var _ = Describe("Errors test", func() {
 	Context("validate error logic", func() {
 		It("can create array tree", func() {
@@ -96,4 +110,78 @@ var _ = Describe("Errors test", func() {
 			Expect(result).To(Equal(expected))
 		})
 	})
+
+	Context("Error handling", func() {
+		var (
+			api    *API
+			source *failingSource
+			rec    *httptest.ResponseRecorder
+		)
+
+		BeforeEach(func() {
+			source = &failingSource{err: errNotFound}
+			api = NewAPI("v1")
+			api.AddResource(Post{}, source)
+			rec = httptest.NewRecorder()
+		})
+
+		get := func() {
+			req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+			Expect(err).ToNot(HaveOccurred())
+			api.Handler().ServeHTTP(rec, req)
+		}
+
+		It("passes the request, resource, action and error to the error handler", func() {
+			var (
+				request *http.Request
+				info    ErrorInfo
+			)
+			api.SetErrorHandler(func(r *http.Request, i ErrorInfo) {
+				request = r
+				info = i
+			})
+			get()
+			Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+			Expect(request.URL.Path).To(Equal("/v1/posts/1"))
+			Expect(info).To(Equal(ErrorInfo{Resource: "posts", Action: ActionFindOne, Err: errNotFound}))
+		})
+
+		It("unwraps the internal error of an HTTPError", func() {
+			source.err = NewHTTPError(errNotFound, "post not found", http.StatusNotFound)
+			var info ErrorInfo
+			api.SetErrorHandler(func(r *http.Request, i ErrorInfo) {
+				info = i
+			})
+			get()
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(errors.Is(info.Err, errNotFound)).To(BeTrue())
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"404","title":"post not found"}]}`))
+		})
+
+		It("logs errors with the logger", func() {
+			var buffer bytes.Buffer
+			api.SetLogger(slog.New(slog.NewJSONHandler(&buffer, nil)))
+			get()
+
+			var entry map[string]interface{}
+			Expect(json.Unmarshal(buffer.Bytes(), &entry)).To(Succeed())
+			Expect(entry).To(HaveKeyWithValue("level", "ERROR"))
+			Expect(entry).To(HaveKeyWithValue("method", "GET"))
+			Expect(entry).To(HaveKeyWithValue("resource", "posts"))
+			Expect(entry).To(HaveKeyWithValue("action", "findOne"))
+			Expect(entry).To(HaveKeyWithValue("error", "record not found"))
+		})
+
+		It("maps errors with the error mapper", func() {
+			api.SetErrorMapper(func(err error) HTTPError {
+				if errors.Is(err, errNotFound) {
+					return NewHTTPError(err, "Not Found", http.StatusNotFound)
+				}
+				return NewHTTPError(err, "Internal Server Error", http.StatusInternalServerError)
+			})
+			get()
+			Expect(rec.Code).To(Equal(http.StatusNotFound))
+			Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"404","title":"Not Found"}]}`))
+		})
+	})
 })
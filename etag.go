@@ -0,0 +1,142 @@
+package api2go
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+// The Versioned interface can be optionally implemented by resources to use
+// their version as ETag instead of a hash of the response, e.g. a revision
+// number or the time of the last update. The version must change whenever the
+// resource changes and must not contain double quotes.
+type Versioned interface {
+	Version() string
+}
+
+// computeETag returns a strong ETag for a marshalled response
+func computeETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// versionETag returns the ETag of a Versioned resource. The query parameters
+// are mixed in because include and fields change the response.
+func versionETag(obj interface{}, query url.Values) (string, bool) {
+	versioned, ok := obj.(Versioned)
+	if !ok {
+		return "", false
+	}
+
+	if len(query) == 0 {
+		return `"` + versioned.Version() + `"`, true
+	}
+
+	sum := sha256.Sum256([]byte(query.Encode()))
+	return `"` + versioned.Version() + "-" + hex.EncodeToString(sum[:8]) + `"`, true
+}
+
+// matchETag reports whether the If-Match or If-None-Match header contains
+// etag. Weak ETags only match with the weak comparison used by If-None-Match.
+func matchETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+
+		if candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resourceETag returns the ETag a GET request of the single resource without
+// query parameters responds with. ETags of responses to requests with query
+// parameters like include never match it.
+func (res *resource) resourceETag(c APIContexter, obj Responder, info information, r *http.Request) (string, error) {
+	if etag, ok := versionETag(obj.Result(), nil); ok {
+		return etag, nil
+	}
+
+	identifier, ok := obj.Result().(jsonapi.MarshalIdentifier)
+	if !ok {
+		return "", nil
+	}
+
+	resourceRequest := r.Clone(r.Context())
+	resourceRequest.URL.Path = res.api.routePrefix() + "/" + res.name + "/" + identifier.GetID()
+	resourceRequest.URL.RawQuery = ""
+	resourceRequest = withIncludePaths(resourceRequest)
+
+	data, err := res.document(c, obj, info, resourceRequest)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	return computeETag(body), nil
+}
+
+// checkIfMatch compares the If-Match header of a request that changes the
+// resource with its current ETag and returns 412 Precondition Failed if it
+// does not match
+func (res *resource) checkIfMatch(c APIContexter, obj Responder, info information, r *http.Request) error {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	etag := ""
+	if obj != nil && obj.Result() != nil {
+		var err error
+		if etag, err = res.resourceETag(c, obj, info, r); err != nil {
+			return err
+		}
+	}
+
+	if etag == "" || !matchETag(header, etag, false) {
+		return NewHTTPError(nil, "Precondition Failed", http.StatusPreconditionFailed)
+	}
+
+	return nil
+}
+
+// checkIfMatchByID loads the resource with FindOne to check the If-Match
+// header of requests that do not load the resource otherwise. Without
+// FindOne the header can not be checked and the request fails with 412.
+func (res *resource) checkIfMatchByID(c APIContexter, id string, info information, r *http.Request) error {
+	if r.Header.Get("If-Match") == "" {
+		return nil
+	}
+
+	getter, ok := sourceAs[ResourceGetter](res.source)
+	if !ok {
+		return NewHTTPError(nil, "Precondition Failed", http.StatusPreconditionFailed)
+	}
+
+	obj, err := getter.FindOne(id, buildRequest(c, r))
+	if err != nil {
+		return err
+	}
+
+	return res.checkIfMatch(c, obj, info, r)
+}
@@ -0,0 +1,193 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type Article struct {
+	ID       string `json:"-"`
+	Title    string `json:"title"`
+	Revision int    `json:"-"`
+}
+
+func (a Article) GetID() string {
+	return a.ID
+}
+
+func (a *Article) SetID(ID string) error {
+	a.ID = ID
+	return nil
+}
+
+func (a Article) Version() string {
+	return strconv.Itoa(a.Revision)
+}
+
+type articleSource struct {
+	articles map[string]Article
+}
+
+func (s *articleSource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: s.articles[ID]}, nil
+}
+
+func (s *articleSource) Update(obj interface{}, req Request) (Responder, error) {
+	article := obj.(Article)
+	article.Revision++
+	s.articles[article.ID] = article
+	return &Response{Res: article, Code: http.StatusOK}, nil
+}
+
+type deleteOnlySource struct {
+	deleted []string
+}
+
+func (s *deleteOnlySource) Delete(id string, req Request) (Responder, error) {
+	s.deleted = append(s.deleted, id)
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+var _ = Describe("ETags", func() {
+	var (
+		api    *API
+		source *fixtureSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}, false}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		api.AddResource(Article{}, &articleSource{map[string]Article{"1": {ID: "1", Title: "Versioned", Revision: 3}}})
+		rec = httptest.NewRecorder()
+	})
+
+	request := func(method, url, body string, header http.Header) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec = httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	currentETag := func(url string) string {
+		request("GET", url, "", nil)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		return rec.Header().Get("ETag")
+	}
+
+	It("answers GET requests with a matching If-None-Match with 304", func() {
+		etag := currentETag("/v1/posts/1")
+		Expect(etag).To(MatchRegexp(`^"[0-9a-f]{32}"$`))
+
+		request("GET", "/v1/posts/1", "", http.Header{"If-None-Match": {`"other", ` + etag}})
+		Expect(rec.Code).To(Equal(http.StatusNotModified))
+		Expect(rec.Header().Get("ETag")).To(Equal(etag))
+		Expect(rec.Body.String()).To(BeEmpty())
+
+		request("GET", "/v1/posts/1", "", http.Header{"If-None-Match": {"W/" + etag}})
+		Expect(rec.Code).To(Equal(http.StatusNotModified))
+
+		source.posts["1"].Title = "Changed"
+		request("GET", "/v1/posts/1", "", http.Header{"If-None-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("ETag")).ToNot(Equal(etag))
+	})
+
+	It("adds ETags to collections", func() {
+		etag := currentETag("/v1/posts")
+		Expect(etag).ToNot(Equal(currentETag("/v1/posts/1")))
+
+		request("GET", "/v1/posts", "", http.Header{"If-None-Match": {"*"}})
+		Expect(rec.Code).To(Equal(http.StatusNotModified))
+	})
+
+	It("rejects updates with a mismatching If-Match with 412", func() {
+		etag := currentETag("/v1/posts/1")
+		body := `{"data": {"type": "posts", "id": "1", "attributes": {"title": "New Title"}}}`
+
+		request("PATCH", "/v1/posts/1", body, http.Header{"If-Match": {`"outdated"`}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors":[{"status":"412","title":"Precondition Failed"}]}`))
+		Expect(source.posts["1"].Title).To(Equal("Hello, World!"))
+
+		request("PATCH", "/v1/posts/1", body, http.Header{"If-Match": {"W/" + etag}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+
+		request("PATCH", "/v1/posts/1", body, http.Header{"If-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.posts["1"].Title).To(Equal("New Title"))
+
+		request("PATCH", "/v1/posts/1", body, http.Header{"If-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+	})
+
+	It("checks If-Match on relationship updates", func() {
+		request("PATCH", "/v1/posts/1/relationships/author", `{"data": null}`, http.Header{"If-Match": {`"outdated"`}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+	})
+
+	It("checks If-Match on deletes", func() {
+		request("DELETE", "/v1/posts/1", "", http.Header{"If-Match": {`"outdated"`}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+		Expect(source.posts).To(HaveKey("1"))
+
+		request("DELETE", "/v1/posts/1", "", http.Header{"If-Match": {"*"}})
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.posts).ToNot(HaveKey("1"))
+	})
+
+	It("uses the version of Versioned resources", func() {
+		Expect(currentETag("/v1/articles/1")).To(Equal(`"3"`))
+
+		request("PATCH", "/v1/articles/1", `{"data": {"type": "articles", "id": "1", "attributes": {"title": "New"}}}`, http.Header{"If-Match": {`"3"`}})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("ETag")).To(Equal(`"4"`))
+
+		request("GET", "/v1/articles/1", "", http.Header{"If-None-Match": {`"4"`}})
+		Expect(rec.Code).To(Equal(http.StatusNotModified))
+	})
+	It("mixes the query parameters into the version", func() {
+		etag := currentETag("/v1/articles/1?fields[articles]=title")
+		Expect(etag).To(HavePrefix(`"3-`))
+		Expect(currentETag("/v1/articles/1")).To(Equal(`"3"`))
+
+		request("GET", "/v1/articles/1?fields[articles]=title", "", http.Header{"If-None-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusNotModified))
+
+		request("GET", "/v1/articles/1", "", http.Header{"If-None-Match": {etag}})
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+
+	It("only accepts the ETag of requests without query parameters in If-Match", func() {
+		body := `{"data": {"type": "posts", "id": "1", "attributes": {"title": "New Title"}}}`
+		request("PATCH", "/v1/posts/1", body, http.Header{"If-Match": {currentETag("/v1/posts/1?fields[posts]=title")}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+
+		body = `{"data": {"type": "articles", "id": "1", "attributes": {"title": "New"}}}`
+		request("PATCH", "/v1/articles/1", body, http.Header{"If-Match": {currentETag("/v1/articles/1?fields[articles]=title")}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+	})
+	It("rejects If-Match if the source can not load the resource", func() {
+		deleter := &deleteOnlySource{}
+		api.AddResource(User{}, deleter)
+
+		request("DELETE", "/v1/users/1", "", http.Header{"If-Match": {"*"}})
+		Expect(rec.Code).To(Equal(http.StatusPreconditionFailed))
+		Expect(deleter.deleted).To(BeEmpty())
+
+		request("DELETE", "/v1/users/1", "", nil)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(deleter.deleted).To(Equal([]string{"1"}))
+	})
+})
@@ -93,6 +93,8 @@ func (u *User) DeleteToManyIDs(name string, IDs []string) error {
 				}
 			}
 		}
+
+		return nil
 	}
 
 	return errors.New("There is no to-many relationship with the name " + name)
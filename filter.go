@@ -0,0 +1,154 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+const (
+	codeInvalidFilter = "API2GO_INVALID_FILTER_QUERY_PARAM"
+
+	// DefaultFilterOperator is used for filters without an explicit operator
+	// like `filter[name]=foo`
+	DefaultFilterOperator = "eq"
+)
+
+// listFilterOperators are the operators whose values are split by comma, the
+// values of all other operators are kept as they are
+var listFilterOperators = []string{"in", "nin"}
+
+var queryFilterRegex = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[(\w+)\])?$`)
+
+// Filter is one parsed `filter[...]` query parameter.
+//
+//	filter[name]=foo       Filter{Field: "name", Operator: "eq", Values: ["foo"]}
+//	filter[age][gt]=30     Filter{Field: "age", Operator: "gt", Values: ["30"]}
+//	filter[id][in]=1,2,3   Filter{Field: "id", Operator: "in", Values: ["1", "2", "3"]}
+type Filter struct {
+	Field    string
+	Operator string
+	Values   []string
+}
+
+// Parameter returns the query parameter name the filter was parsed from
+func (f Filter) Parameter() string {
+	if f.Operator == DefaultFilterOperator {
+		return fmt.Sprintf("filter[%s]", f.Field)
+	}
+
+	return fmt.Sprintf("filter[%s][%s]", f.Field, f.Operator)
+}
+
+// parseFilters returns all filters of the request sorted by field and
+// operator, all `filter[` parameters that could not be parsed and all that
+// were repeated. Filters of repeated parameters contain the first value.
+func parseFilters(r *http.Request) (filters []Filter, malformed, repeated []string) {
+	for key, values := range r.URL.Query() {
+		if !strings.HasPrefix(key, "filter[") {
+			continue
+		}
+
+		matches := queryFilterRegex.FindStringSubmatch(key)
+		if matches == nil {
+			malformed = append(malformed, key)
+			continue
+		}
+
+		if len(values) > 1 {
+			repeated = append(repeated, key)
+		}
+
+		operator := matches[2]
+		if operator == "" {
+			operator = DefaultFilterOperator
+		}
+
+		filterValues := []string{values[0]}
+		if containsString(listFilterOperators, operator) {
+			filterValues = strings.Split(values[0], ",")
+		}
+
+		filters = append(filters, Filter{
+			Field:    matches[1],
+			Operator: operator,
+			Values:   filterValues,
+		})
+	}
+
+	sort.Slice(filters, func(i, j int) bool {
+		if filters[i].Field != filters[j].Field {
+			return filters[i].Field < filters[j].Field
+		}
+		return filters[i].Operator < filters[j].Operator
+	})
+	sort.Strings(malformed)
+	sort.Strings(repeated)
+
+	return
+}
+
+// validateFilters returns a 400 HTTPError if the source implements
+// FilterableResource and the request contains filters that are malformed,
+// repeated or not allowed.
+func validateFilters(source interface{}, r *http.Request) error {
+	filterable, ok := sourceAs[FilterableResource](source)
+	if !ok {
+		return nil
+	}
+
+	allowed := filterable.FilterableFields()
+	filters, malformed, repeated := parseFilters(r)
+
+	httpError := NewHTTPError(nil, "Some requested filters were invalid", http.StatusBadRequest)
+	newError := func(parameter, title string) jsonapi.Error {
+		return jsonapi.Error{
+			Status: strconv.Itoa(http.StatusBadRequest),
+			Code:   codeInvalidFilter,
+			Title:  title,
+			Detail: "Please make sure you do only use supported filters",
+			Source: &jsonapi.ErrorSource{
+				Parameter: parameter,
+			},
+		}
+	}
+
+	for _, parameter := range repeated {
+		httpError.Errors = append(httpError.Errors, newError(parameter, fmt.Sprintf(`Filter "%s" is repeated`, parameter)))
+	}
+
+	for _, parameter := range malformed {
+		httpError.Errors = append(httpError.Errors, newError(parameter, fmt.Sprintf(`Filter "%s" is malformed`, parameter)))
+	}
+
+	for _, filter := range filters {
+		operators, ok := allowed[filter.Field]
+		if !ok {
+			httpError.Errors = append(httpError.Errors, newError(filter.Parameter(), fmt.Sprintf(`Filtering by "%s" is not supported`, filter.Field)))
+			continue
+		}
+
+		supported := false
+		for _, operator := range operators {
+			if operator == filter.Operator {
+				supported = true
+				break
+			}
+		}
+
+		if !supported {
+			httpError.Errors = append(httpError.Errors, newError(filter.Parameter(), fmt.Sprintf(`Operator "%s" is not supported for filter "%s"`, filter.Operator, filter.Field)))
+		}
+	}
+
+	if len(httpError.Errors) > 0 {
+		return httpError
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type filterableSource struct {
+	fixtureSource
+	filters []Filter
+}
+
+func (s *filterableSource) FilterableFields() map[string][]string {
+	return map[string][]string{
+		"title": {DefaultFilterOperator, "like"},
+		"value": {"gt", "lt"},
+	}
+}
+
+func (s *filterableSource) FindAll(req Request) (Responder, error) {
+	s.filters = req.Filters
+	return s.fixtureSource.FindAll(req)
+}
+
+var _ = Describe("Filtering", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *filterableSource
+	)
+
+	BeforeEach(func() {
+		source = &filterableSource{fixtureSource: fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}, false}}
+
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	It("parses filter parameters", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[title]=foo&filter[value][gt]=30&filter[id][in]=1,2&filter[name][eq]=Doe,%20John&filter=raw", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buildRequest(&APIContext{}, req).Filters).To(Equal([]Filter{
+			{Field: "id", Operator: "in", Values: []string{"1", "2"}},
+			{Field: "name", Operator: "eq", Values: []string{"Doe, John"}},
+			{Field: "title", Operator: "eq", Values: []string{"foo"}},
+			{Field: "value", Operator: "gt", Values: []string{"30"}},
+		}))
+	})
+
+	It("passes allowed filters to the source", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[title][like]=Hello&filter[value][lt]=3", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.filters).To(Equal([]Filter{
+			{Field: "title", Operator: "like", Values: []string{"Hello"}},
+			{Field: "value", Operator: "lt", Values: []string{"3"}},
+		}))
+	})
+
+	It("rejects unsupported filters before calling the source", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[title]=a&filter[value]=3&filter[author]=1&filter[a][b][c]=1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(source.filters).To(BeNil())
+
+		var httpError HTTPError
+		Expect(json.Unmarshal(rec.Body.Bytes(), &httpError)).To(Succeed())
+		Expect(httpError.Errors).To(HaveLen(3))
+
+		parameters := []string{}
+		for _, e := range httpError.Errors {
+			Expect(e.Status).To(Equal("400"))
+			Expect(e.Code).To(Equal(codeInvalidFilter))
+			parameters = append(parameters, e.Source.Parameter)
+		}
+		Expect(parameters).To(Equal([]string{"filter[a][b][c]", "filter[author]", "filter[value]"}))
+		Expect(httpError.Errors[2].Title).To(Equal(`Operator "eq" is not supported for filter "value"`))
+	})
+	It("rejects repeated filters of filterable sources", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[title]=a&filter[title]=b", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(source.filters).To(BeNil())
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{
+			"status": "400",
+			"code": "API2GO_INVALID_FILTER_QUERY_PARAM",
+			"title": "Filter \"filter[title]\" is repeated",
+			"detail": "Please make sure you do only use supported filters",
+			"source": {"parameter": "filter[title]"}
+		}]}`))
+	})
+
+	It("passes repeated filters with their first value to sources that are not filterable", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?filter[title]=a&filter[title]=b&filter[a][b][c]=1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(validateFilters(&source.fixtureSource, req)).To(Succeed())
+		Expect(buildRequest(&APIContext{}, req).Filters).To(Equal([]Filter{
+			{Field: "title", Operator: "eq", Values: []string{"a"}},
+		}))
+	})
+})
@@ -0,0 +1,265 @@
+package api2go
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+const codeInvalidInclude = "API2GO_INVALID_INCLUDE_QUERY_PARAM"
+
+// includeQuery contains the parsed include query parameter of a request and
+// the roots it was already checked for
+type includeQuery struct {
+	paths   []string
+	present bool
+	checked map[string]bool
+}
+
+type includeContextKey struct{}
+
+// withIncludePaths parses the include query parameter of r once and returns r
+// with the result, which includePaths reads afterwards.
+func withIncludePaths(r *http.Request) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), includeContextKey{}, newIncludeQuery(r)))
+}
+
+func newIncludeQuery(r *http.Request) *includeQuery {
+	paths, present := parseIncludePaths(r.URL.Query())
+	return &includeQuery{paths: paths, present: present, checked: map[string]bool{}}
+}
+
+func getIncludeQuery(r *http.Request) *includeQuery {
+	if query, ok := r.Context().Value(includeContextKey{}).(*includeQuery); ok {
+		return query
+	}
+
+	return newIncludeQuery(r)
+}
+
+// includePaths returns the paths of the include query parameter of r and
+// whether the parameter was present at all.
+func includePaths(r *http.Request) ([]string, bool) {
+	query := getIncludeQuery(r)
+	return query.paths, query.present
+}
+
+// parseIncludePaths returns the comma separated paths of all include query
+// parameters and whether the parameter was present at all.
+func parseIncludePaths(query url.Values) ([]string, bool) {
+	values, ok := query["include"]
+	if !ok {
+		return nil, false
+	}
+
+	paths := []string{}
+	for _, value := range values {
+		for _, path := range strings.Split(value, ",") {
+			path = strings.TrimSpace(path)
+			if path != "" && !containsString(paths, path) {
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	return paths, true
+}
+
+func newInvalidIncludeError(paths []string) HTTPError {
+	httpError := NewHTTPError(nil, "Some requested include paths were invalid", http.StatusBadRequest)
+	for _, path := range paths {
+		httpError.Errors = append(httpError.Errors, jsonapi.Error{
+			Status: strconv.Itoa(http.StatusBadRequest),
+			Code:   codeInvalidInclude,
+			Title:  fmt.Sprintf(`Relationship path "%s" can not be included`, path),
+			Detail: "Please make sure you do only include existing relationships",
+			Source: &jsonapi.ErrorSource{
+				Parameter: "include",
+			},
+		})
+	}
+
+	return httpError
+}
+
+// findResource returns the registered resource with the given type name
+func (api *API) findResource(name string) *resource {
+	for _, res := range api.resources {
+		if res.name == name {
+			return res
+		}
+	}
+
+	return nil
+}
+
+// references returns all possible relationships of the resource prototype
+func (res *resource) references() []jsonapi.Reference {
+	if casted, ok := res.prototype.(jsonapi.MarshalReferences); ok {
+		return casted.GetReferences()
+	}
+
+	return nil
+}
+
+// referencedResources returns the registered resources a relationship can
+// point to, these are all of its types for polymorphic relationships.
+func (api *API) referencedResources(reference jsonapi.Reference) []*resource {
+	types := []string{reference.Type}
+	if reference.IsPolymorphic() {
+		types = reference.Types
+	}
+
+	var result []*resource
+	for _, name := range types {
+		if res := api.findResource(name); res != nil {
+			result = append(result, res)
+		}
+	}
+
+	return result
+}
+
+// checkIncludePaths validates all include paths against the references of the
+// roots and the registered resources they point to. A segment is valid if one
+// of the resources of the previous segment has a relationship with its name.
+func (api *API) checkIncludePaths(roots []*resource, paths []string) error {
+	var invalidPaths []string
+
+	for _, path := range paths {
+		current := roots
+
+		for _, segment := range strings.Split(path, ".") {
+			var (
+				next  []*resource
+				found bool
+			)
+			for _, res := range current {
+				for _, reference := range res.references() {
+					if reference.Name == segment {
+						found = true
+						next = append(next, api.referencedResources(reference)...)
+					}
+				}
+			}
+
+			if !found {
+				invalidPaths = append(invalidPaths, path)
+				break
+			}
+			current = next
+		}
+	}
+
+	if len(invalidPaths) > 0 {
+		return newInvalidIncludeError(invalidPaths)
+	}
+
+	return nil
+}
+
+// validateIncludes returns a 400 HTTPError if the request asks to include
+// relationships that do not exist for any of the roots. The paths are checked
+// only once per request and roots.
+func (api *API) validateIncludes(roots []*resource, r *http.Request) error {
+	query := getIncludeQuery(r)
+	if !query.present {
+		return nil
+	}
+
+	names := make([]string, len(roots))
+	for i, root := range roots {
+		names[i] = root.name
+	}
+	key := strings.Join(names, ",")
+	if query.checked[key] {
+		return nil
+	}
+
+	if err := api.checkIncludePaths(roots, query.paths); err != nil {
+		return err
+	}
+	query.checked[key] = true
+
+	return nil
+}
+
+// validateIncludes returns a 400 HTTPError if the request asks to include
+// relationships of res that do not exist.
+func (res *resource) validateIncludes(r *http.Request) error {
+	return res.api.validateIncludes([]*resource{res}, r)
+}
+
+// resolveInclude returns a jsonapi ResolveInclude function which loads
+// referenced structs that are not returned by GetReferencedStructs via FindOne
+// of the registered resource, one call per struct. The query parameters of r
+// are not passed to FindOne.
+func (api *API) resolveInclude(c APIContexter, r *http.Request) func(string, string) (jsonapi.MarshalIdentifier, error) {
+	req := includeRequest(c, r)
+
+	return func(referenceType, id string) (jsonapi.MarshalIdentifier, error) {
+		target := api.findResource(referenceType)
+		if target == nil {
+			return nil, NewHTTPError(nil, "No resource handler is registered to handle the included resource "+referenceType, http.StatusInternalServerError)
+		}
+
+		source, ok := sourceAs[ResourceGetter](target.source)
+		if !ok {
+			return nil, fmt.Errorf("Resource %s does not implement the ResourceGetter interface", target.name)
+		}
+
+		response, err := source.FindOne(id, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if found := toMarshalIdentifiers(response.Result()); len(found) > 0 {
+			return found[0], nil
+		}
+
+		return nil, nil
+	}
+}
+
+// includeRequest returns the Request to load included structs with, which only
+// contains the context and headers of r but none of its query parameters
+func includeRequest(c APIContexter, r *http.Request) Request {
+	plain := r.Clone(r.Context())
+	plain.URL.RawQuery = ""
+
+	return Request{PlainRequest: plain, Header: r.Header, Context: c}
+}
+
+// toMarshalIdentifiers converts a Responder result which can either be a single
+// struct, a pointer or a slice into a list of MarshalIdentifiers
+func toMarshalIdentifiers(result interface{}) []jsonapi.MarshalIdentifier {
+	if result == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(result)
+	if value.Kind() != reflect.Slice {
+		if value.Kind() == reflect.Ptr && value.IsNil() {
+			return nil
+		}
+		if casted, ok := result.(jsonapi.MarshalIdentifier); ok {
+			return []jsonapi.MarshalIdentifier{casted}
+		}
+		return nil
+	}
+
+	identifiers := make([]jsonapi.MarshalIdentifier, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		if casted, ok := value.Index(i).Interface().(jsonapi.MarshalIdentifier); ok {
+			identifiers = append(identifiers, casted)
+		}
+	}
+
+	return identifiers
+}
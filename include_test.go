@@ -0,0 +1,265 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type IncludeArticle struct {
+	ID         string   `json:"-"`
+	Title      string   `json:"title"`
+	AuthorID   string   `json:"-"`
+	CommentIDs []string `json:"-"`
+}
+
+func (a IncludeArticle) GetID() string {
+	return a.ID
+}
+
+func (a IncludeArticle) GetName() string {
+	return "articles"
+}
+
+func (a IncludeArticle) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{
+		{Name: "author", Type: "people"},
+		{Name: "comments", Type: "comments"},
+	}
+}
+
+func (a IncludeArticle) GetReferencedIDs() []jsonapi.ReferenceID {
+	result := []jsonapi.ReferenceID{}
+	if a.AuthorID != "" {
+		result = append(result, jsonapi.ReferenceID{ID: a.AuthorID, Name: "author", Type: "people"})
+	}
+	for _, id := range a.CommentIDs {
+		result = append(result, jsonapi.ReferenceID{ID: id, Name: "comments", Type: "comments"})
+	}
+	return result
+}
+
+type IncludeComment struct {
+	ID       string `json:"-"`
+	Body     string `json:"body"`
+	AuthorID string `json:"-"`
+}
+
+func (c IncludeComment) GetID() string {
+	return c.ID
+}
+
+func (c IncludeComment) GetName() string {
+	return "comments"
+}
+
+func (c IncludeComment) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{{Name: "author", Type: "people"}}
+}
+
+func (c IncludeComment) GetReferencedIDs() []jsonapi.ReferenceID {
+	if c.AuthorID == "" {
+		return []jsonapi.ReferenceID{}
+	}
+	return []jsonapi.ReferenceID{{ID: c.AuthorID, Name: "author", Type: "people"}}
+}
+
+type IncludePerson struct {
+	ID   string `json:"-"`
+	Name string `json:"name"`
+}
+
+func (p IncludePerson) GetID() string {
+	return p.ID
+}
+
+func (p IncludePerson) GetName() string {
+	return "people"
+}
+
+type includeArticleSource struct {
+	articles []IncludeArticle
+	calls    int
+}
+
+func (s *includeArticleSource) FindAll(req Request) (Responder, error) {
+	s.calls++
+	return &Response{Res: s.articles}, nil
+}
+
+func (s *includeArticleSource) FindOne(ID string, req Request) (Responder, error) {
+	s.calls++
+	for _, article := range s.articles {
+		if article.ID == ID {
+			return &Response{Res: article}, nil
+		}
+	}
+	return nil, NewHTTPError(nil, "article not found", http.StatusNotFound)
+}
+
+type includeCommentSource struct {
+	comments map[string]IncludeComment
+}
+
+func (s *includeCommentSource) FindOne(ID string, req Request) (Responder, error) {
+	if comment, ok := s.comments[ID]; ok {
+		return &Response{Res: comment}, nil
+	}
+	return nil, NewHTTPError(nil, "comment not found", http.StatusNotFound)
+}
+
+type includePersonSource struct {
+	people   map[string]IncludePerson
+	calls    int
+	requests []Request
+}
+
+func (s *includePersonSource) FindOne(ID string, req Request) (Responder, error) {
+	s.calls++
+	s.requests = append(s.requests, req)
+	if person, ok := s.people[ID]; ok {
+		return &Response{Res: &person}, nil
+	}
+	return nil, NewHTTPError(nil, "person not found", http.StatusNotFound)
+}
+
+var _ = Describe("Including related resources", func() {
+	var (
+		api            *API
+		rec            *httptest.ResponseRecorder
+		articleSource  *includeArticleSource
+		personSource   *includePersonSource
+		commentsSource *includeCommentSource
+	)
+
+	BeforeEach(func() {
+		articleSource = &includeArticleSource{articles: []IncludeArticle{
+			{ID: "1", Title: "First", AuthorID: "9", CommentIDs: []string{"5", "12"}},
+		}}
+		commentsSource = &includeCommentSource{comments: map[string]IncludeComment{
+			"5":  {ID: "5", Body: "First!", AuthorID: "2"},
+			"12": {ID: "12", Body: "I like XML better", AuthorID: "9"},
+		}}
+		personSource = &includePersonSource{people: map[string]IncludePerson{
+			"2": {ID: "2", Name: "Dan"},
+			"9": {ID: "9", Name: "Dieter"},
+		}}
+
+		api = NewAPI("v1")
+		api.AddResource(IncludeArticle{}, articleSource)
+		api.AddResource(IncludeComment{}, commentsSource)
+		api.AddResource(IncludePerson{}, personSource)
+		rec = httptest.NewRecorder()
+	})
+
+	included := func() []map[string]interface{} {
+		var result struct {
+			Included []map[string]interface{} `json:"included"`
+		}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		return result.Included
+	}
+
+	It("includes nothing without include parameter", func() {
+		req, err := http.NewRequest("GET", "/v1/articles/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(included()).To(BeEmpty())
+	})
+
+	It("includes a to-one relationship", func() {
+		req, err := http.NewRequest("GET", "/v1/articles/1?include=author", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(included()).To(Equal([]map[string]interface{}{
+			{"type": "people", "id": "9", "attributes": map[string]interface{}{"name": "Dieter"}},
+		}))
+	})
+
+	It("includes nested relationship paths without duplicates", func() {
+		req, err := http.NewRequest("GET", "/v1/articles?include=author,comments.author", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		result := included()
+		Expect(result).To(HaveLen(4))
+		types := []string{}
+		for _, entry := range result {
+			types = append(types, entry["type"].(string)+":"+entry["id"].(string))
+		}
+		Expect(types).To(Equal([]string{"people:9", "comments:5", "comments:12", "people:2"}))
+		Expect(personSource.calls).To(Equal(2))
+	})
+
+	It("returns an empty included list for an empty include parameter", func() {
+		req, err := http.NewRequest("GET", "/v1/articles/1?include=", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(included()).To(BeEmpty())
+	})
+
+	It("rejects unknown include paths before calling the source", func() {
+		req, err := http.NewRequest("GET", "/v1/articles?include=author,comments.likes,unicorns", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(articleSource.calls).To(Equal(0))
+
+		var httpError HTTPError
+		Expect(json.Unmarshal(rec.Body.Bytes(), &httpError)).To(Succeed())
+		Expect(httpError.Errors).To(HaveLen(2))
+		Expect(httpError.Errors[0].Title).To(Equal(`Relationship path "comments.likes" can not be included`))
+		Expect(httpError.Errors[0].Status).To(Equal("400"))
+		Expect(httpError.Errors[0].Source).To(Equal(&jsonapi.ErrorSource{Parameter: "include"}))
+		Expect(httpError.Errors[1].Title).To(Equal(`Relationship path "unicorns" can not be included`))
+	})
+
+	It("passes the include paths to the source", func() {
+		req, err := http.NewRequest("GET", "/v1/articles?include=author,comments.author", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api2goReq := buildRequest(&APIContext{}, req)
+		Expect(api2goReq.Include).To(Equal([]string{"author", "comments.author"}))
+	})
+	It("combines repeated include parameters", func() {
+		req, err := http.NewRequest("GET", "/v1/articles/1?include=author&include=comments,author", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buildRequest(&APIContext{}, withIncludePaths(req)).Include).To(Equal([]string{"author", "comments"}))
+
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(included()).To(HaveLen(3))
+
+		rec = httptest.NewRecorder()
+		req, err = http.NewRequest("GET", "/v1/articles/1?include=author&include=unicorns", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`Relationship path \"unicorns\" can not be included`))
+	})
+	It("loads included structs without the query parameters of the request", func() {
+		req, err := http.NewRequest("GET", "/v1/articles/1?include=author&sort=title&filter[title]=First&page[number]=1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer secret")
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(personSource.requests).To(HaveLen(1))
+
+		lookup := personSource.requests[0]
+		Expect(lookup.Header.Get("Authorization")).To(Equal("Bearer secret"))
+		Expect(lookup.Context).ToNot(BeNil())
+		Expect(lookup.PlainRequest.URL.RawQuery).To(BeEmpty())
+		Expect(lookup.QueryParams).To(BeEmpty())
+		Expect(lookup.Include).To(BeNil())
+		Expect(lookup.Sort).To(BeEmpty())
+		Expect(lookup.Filters).To(BeEmpty())
+		Expect(lookup.Pagination).To(BeEmpty())
+	})
+})
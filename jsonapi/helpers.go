@@ -1,10 +1,12 @@
 package jsonapi
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
+	"reflect"
 	"regexp"
 	"strings"
 	"unicode"
@@ -69,6 +71,22 @@ func Pluralize(word string) string {
 	return inflector.Pluralize(word)
 }
 
+// EntityName returns the name of the routes of a resource, which is the name
+// of EntityNamer or the jsonified plural of the name of its struct type. The
+// api registers the routes and the client requests them with it.
+func EntityName(obj interface{}) string {
+	if entityName, ok := obj.(EntityNamer); ok {
+		return entityName.GetName()
+	}
+
+	reflectType := reflect.TypeOf(obj)
+	if reflectType.Kind() == reflect.Ptr {
+		reflectType = reflectType.Elem()
+	}
+
+	return Jsonify(Pluralize(reflectType.Name()))
+}
+
 var (
 	queryFieldsRegex = regexp.MustCompile(`^fields\[(\w+)\]$`)
 )
@@ -81,6 +99,10 @@ const (
 
 // FilterSparseFields returns a document with only the specific fields in the response on a per-type basis.
 // https://jsonapi.org/format/#fetching-sparse-fieldsets
+//
+// MarshalToStructWithOptions with MarshalOptions.Fields applies the fields while
+// marshalling instead of decoding and encoding the attributes of the document
+// again.
 func FilterSparseFields(resp interface{}, queryParams map[string][]string) (interface{}, []Error) {
 	if len(queryParams) < 1 {
 		return resp, nil
@@ -123,26 +145,179 @@ func FilterSparseFields(resp interface{}, queryParams map[string][]string) (inte
 	}
 
 	if len(wrongFields) > 0 {
-		var errs []Error
-		//httpError := NewHTTPError(nil, "Some requested fields were invalid", http.StatusBadRequest)
-		for k, v := range wrongFields {
-			for _, field := range v {
-				errs = append(errs, Error{
-					Status: "Bad Request",
-					Code:   codeInvalidQueryFields,
-					Title:  fmt.Sprintf(`Field "%s" does not exist for type "%s"`, field, k),
-					Detail: "Please make sure you do only request existing fields",
-					Source: &ErrorSource{
-						Parameter: fmt.Sprintf("fields[%s]", k),
-					},
-				})
-			}
-		}
-		return nil, errs
+		return nil, (&InvalidFieldsError{Fields: wrongFields}).Errors()
 	}
 	return resp, nil
 }
 
+// InvalidFieldsError is returned by MarshalToStructWithOptions if fields of a
+// type were requested that do not exist. Fields contains them by type.
+type InvalidFieldsError struct {
+	Fields map[string][]string
+}
+
+func (e *InvalidFieldsError) Error() string {
+	return ErrRequestedInvalidFields.Error()
+}
+
+func (e *InvalidFieldsError) Unwrap() error {
+	return ErrRequestedInvalidFields
+}
+
+// Errors returns one error object per invalid field
+func (e *InvalidFieldsError) Errors() []Error {
+	var errs []Error
+	for k, v := range e.Fields {
+		for _, field := range v {
+			errs = append(errs, Error{
+				Status: "Bad Request",
+				Code:   codeInvalidQueryFields,
+				Title:  fmt.Sprintf(`Field "%s" does not exist for type "%s"`, field, k),
+				Detail: "Please make sure you do only request existing fields",
+				Source: &ErrorSource{
+					Parameter: fmt.Sprintf("fields[%s]", k),
+				},
+			})
+		}
+	}
+
+	return errs
+}
+
+// fieldset selects the requested attributes of each type while marshalling
+// and collects the requested fields that do not exist
+type fieldset struct {
+	fields  map[string][]string
+	invalid map[string][]string
+}
+
+func newFieldset(fields map[string][]string) *fieldset {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return &fieldset{fields: fields, invalid: map[string][]string{}}
+}
+
+// marshalAttributes marshals the requested fields of the attributes of a
+// resource with the type resourceType. Requested fields that are neither
+// attributes nor one of the relationships are invalid. A nil fieldset
+// marshals all attributes.
+func (f *fieldset) marshalAttributes(element MarshalIdentifier, resourceType string, relationships []string) ([]byte, error) {
+	attributes, err := json.Marshal(element)
+	if err != nil || f == nil || f.fields[resourceType] == nil {
+		return attributes, err
+	}
+
+	attributes, unknown, err := selectAttributes(attributes, f.fields[resourceType])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range unknown {
+		if !contains(relationships, field) {
+			f.addInvalid(resourceType, field)
+		}
+	}
+
+	return attributes, nil
+}
+
+// selectAttributes keeps only the named members of a marshalled JSON object
+// in their order without encoding their values again, and returns the names
+// that are not members of it
+func selectAttributes(attributes []byte, names []string) ([]byte, []string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(attributes))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, nil, fmt.Errorf("attributes must be marshalled to a JSON object: %s", attributes)
+	}
+
+	known := map[string]bool{}
+	buffer := bytes.NewBufferString("{")
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+
+		name := token.(string)
+		known[name] = true
+		if !contains(names, name) {
+			continue
+		}
+
+		key, _ := json.Marshal(name)
+		if buffer.Len() > 1 {
+			buffer.WriteByte(',')
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+
+	var unknown []string
+	for _, name := range names {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	return buffer.Bytes(), unknown, nil
+}
+
+// filterRelationships returns only the requested relationships of a resource
+// with the type resourceType
+func (f *fieldset) filterRelationships(resourceType string, relationships map[string]Relationship) map[string]Relationship {
+	if f == nil {
+		return relationships
+	}
+
+	fields, ok := f.fields[resourceType]
+	if !ok {
+		return relationships
+	}
+
+	selected := map[string]Relationship{}
+	for name, relationship := range relationships {
+		if contains(fields, name) {
+			selected[name] = relationship
+		}
+	}
+
+	return selected
+}
+
+func (f *fieldset) addInvalid(resourceType, field string) {
+	if !contains(f.invalid[resourceType], field) {
+		f.invalid[resourceType] = append(f.invalid[resourceType], field)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// err returns an *InvalidFieldsError if invalid fields were requested
+func (f *fieldset) err() error {
+	if f == nil || len(f.invalid) == 0 {
+		return nil
+	}
+
+	return &InvalidFieldsError{Fields: f.invalid}
+}
+
 // ParseQueryFields returns a map containing lists of field name(s) to be returned by resource type.
 // https://jsonapi.org/format/#fetching-sparse-fieldsets
 func ParseQueryFields(query *url.Values) (result map[string][]string) {
@@ -158,14 +333,14 @@ func ParseQueryFields(query *url.Values) (result map[string][]string) {
 	return
 }
 
-func filterAttributes(attributes map[string]interface{}, fields []string) (filteredAttributes map[string]interface{}, wrongFields []string) {
+func filterAttributes(attributes map[string]interface{}, relationships map[string]Relationship, fields []string) (filteredAttributes map[string]interface{}, wrongFields []string) {
 	wrongFields = []string{}
 	filteredAttributes = map[string]interface{}{}
 
 	for _, field := range fields {
 		if attribute, ok := attributes[field]; ok {
 			filteredAttributes[field] = attribute
-		} else {
+		} else if _, ok := relationships[field]; !ok {
 			wrongFields = append(wrongFields, field)
 		}
 	}
@@ -180,7 +355,7 @@ func replaceAttributes(query *map[string][]string, entry *Data) map[string][]str
 	fields := (*query)[fieldType]
 	if len(fields) > 0 {
 		var wrongFields []string
-		attributes, wrongFields = filterAttributes(attributes, fields)
+		attributes, wrongFields = filterAttributes(attributes, entry.Relationships, fields)
 		if len(wrongFields) > 0 {
 			return map[string][]string{
 				fieldType: wrongFields,
@@ -188,6 +363,14 @@ func replaceAttributes(query *map[string][]string, entry *Data) map[string][]str
 		}
 		bytes, _ := json.Marshal(attributes)
 		entry.Attributes = bytes
+
+		relationships := map[string]Relationship{}
+		for _, field := range fields {
+			if relationship, ok := entry.Relationships[field]; ok {
+				relationships[field] = relationship
+			}
+		}
+		entry.Relationships = relationships
 	}
 
 	return nil
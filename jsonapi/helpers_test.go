@@ -22,5 +22,13 @@ var _ = Describe("StringHelpers", func() {
 				Expect(Jsonify("RAM")).To(Equal("ram"))
 			})
 		})
+
+		It("names the routes of entities like the api", func() {
+			type URL struct{}
+			Expect(EntityName(SimplePost{})).To(Equal("simplePosts"))
+			Expect(EntityName(&SimplePost{})).To(Equal("simplePosts"))
+			Expect(EntityName(URL{})).To(Equal("uRLs"))
+			Expect(EntityName(RenamedComment{})).To(Equal("renamed-comments"))
+		})
 	})
 })
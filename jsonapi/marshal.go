@@ -44,13 +44,33 @@ type ReferenceID struct {
 // references, but you do not want to load them. Otherwise, if IsNotLoaded is
 // false and GetReferencedIDs() returns no IDs for this reference name, an
 // empty `data` field will be added which means that there are no references.
+//
+// Polymorphic relationships, whose resources can have different types, list
+// all possible types in Types instead of Type. Each ReferenceID of them must
+// declare its own type.
 type Reference struct {
 	Type         string
+	Types        []string
 	Name         string
 	IsNotLoaded  bool
 	Relationship RelationshipType
 }
 
+// IsPolymorphic returns true if the resources of the relationship can have
+// different types
+func (r Reference) IsPolymorphic() bool {
+	return len(r.Types) > 0
+}
+
+// allows reports whether resources of the given type can be referenced
+func (r Reference) allows(resourceType string) bool {
+	if r.IsPolymorphic() {
+		return contains(r.Types, resourceType)
+	}
+
+	return r.Type == "" || r.Type == resourceType
+}
+
 // The MarshalReferences interface must be implemented if the struct to be
 // serialized has relationships.
 type MarshalReferences interface {
@@ -129,15 +149,19 @@ func Marshal(data interface{}) ([]byte, error) {
 // you want to extract or extend parts of the document. You should directly use
 // Marshal to get a []byte with JSON in it.
 func MarshalToStruct(data interface{}, information ServerInformation) (*Document, error) {
+	return MarshalToStructWithOptions(data, serverOptions(information))
+}
+
+func marshalToStruct(data interface{}, options *marshalOptions) (*Document, error) {
 	if data == nil {
 		return &Document{}, nil
 	}
 
 	switch reflect.TypeOf(data).Kind() {
 	case reflect.Slice:
-		return marshalSlice(data, information)
+		return marshalSlice(data, options)
 	case reflect.Struct, reflect.Ptr:
-		return marshalStruct(data.(MarshalIdentifier), information)
+		return marshalStruct(data.(MarshalIdentifier), options)
 	default:
 		return nil, errors.New("Marshal only accepts slice, struct or ptr types")
 	}
@@ -164,12 +188,12 @@ func recursivelyEmbedIncludes(input []MarshalIdentifier) []MarshalIdentifier {
 	return referencedStructs
 }
 
-func marshalSlice(data interface{}, information ServerInformation) (*Document, error) {
+func marshalSlice(data interface{}, options *marshalOptions) (*Document, error) {
 	result := &Document{}
 
 	val := reflect.ValueOf(data)
 	dataElements := make([]Data, val.Len())
-	var referencedStructs []MarshalIdentifier
+	elements := make([]MarshalIdentifier, val.Len())
 
 	for i := 0; i < val.Len(); i++ {
 		k := val.Index(i).Interface()
@@ -178,19 +202,15 @@ func marshalSlice(data interface{}, information ServerInformation) (*Document, e
 			return nil, errors.New("all elements within the slice must implement api2go.MarshalIdentifier")
 		}
 
-		err := marshalData(element, &dataElements[i], information)
+		err := marshalData(element, &dataElements[i], options)
 		if err != nil {
 			return nil, err
 		}
 
-		included, ok := k.(MarshalIncludedRelations)
-		if ok {
-			referencedStructs = append(referencedStructs, included.GetReferencedStructs()...)
-		}
+		elements[i] = element
 	}
 
-	allReferencedStructs := recursivelyEmbedIncludes(referencedStructs)
-	includedElements, err := filterDuplicates(allReferencedStructs, information)
+	includedElements, err := options.included(elements)
 	if err != nil {
 		return nil, err
 	}
@@ -206,7 +226,7 @@ func marshalSlice(data interface{}, information ServerInformation) (*Document, e
 	return result, nil
 }
 
-func filterDuplicates(input []MarshalIdentifier, information ServerInformation) ([]Data, error) {
+func filterDuplicates(input []MarshalIdentifier, options *marshalOptions) ([]Data, error) {
 	alreadyIncluded := map[string]map[string]bool{}
 	includedElements := []Data{}
 
@@ -219,7 +239,7 @@ func filterDuplicates(input []MarshalIdentifier, information ServerInformation)
 
 		if !alreadyIncluded[structType][referencedStruct.GetID()] {
 			var data Data
-			err := marshalData(referencedStruct, &data, information)
+			err := marshalData(referencedStruct, &data, options)
 			if err != nil {
 				return nil, err
 			}
@@ -232,27 +252,34 @@ func filterDuplicates(input []MarshalIdentifier, information ServerInformation)
 	return includedElements, nil
 }
 
-func marshalData(element MarshalIdentifier, data *Data, information ServerInformation) error {
+func marshalData(element MarshalIdentifier, data *Data, options *marshalOptions) error {
 	refValue := reflect.ValueOf(element)
 	if refValue.Kind() == reflect.Ptr && refValue.IsNil() {
 		return errors.New("MarshalIdentifier must not be nil")
 	}
 
-	attributes, err := json.Marshal(element)
+	data.ID = element.GetID()
+	data.Type = getStructType(element)
+
+	var relationships []string
+	if referencer, ok := element.(MarshalReferences); ok {
+		for _, reference := range referencer.GetReferences() {
+			relationships = append(relationships, reference.Name)
+		}
+	}
+
+	var err error
+	data.Attributes, err = options.fields.marshalAttributes(element, data.Type, relationships)
 	if err != nil {
 		return err
 	}
 
-	data.Attributes = attributes
-	data.ID = element.GetID()
-	data.Type = getStructType(element)
-
-	if information != nil {
+	if options.CustomLinks {
 		if customLinks, ok := element.(MarshalCustomLinks); ok {
 			if data.Links == nil {
 				data.Links = make(Links)
 			}
-			base := getLinkBaseURL(element, information)
+			base := getLinkBaseURL(element, options.information)
 			for k, v := range customLinks.GetCustomLinks(base) {
 				if _, ok := data.Links[k]; !ok {
 					data.Links[k] = v
@@ -270,7 +297,7 @@ func marshalData(element MarshalIdentifier, data *Data, information ServerInform
 	}
 
 	if references, ok := element.(MarshalLinkedRelations); ok {
-		data.Relationships = getStructRelationships(references, information)
+		data.Relationships = options.fields.filterRelationships(data.Type, getStructRelationships(references, options))
 	}
 
 	return nil
@@ -297,7 +324,7 @@ func getMetaForRelation(metaSource MarshalCustomRelationshipMeta, name string, i
 	return meta
 }
 
-func getStructRelationships(relationer MarshalLinkedRelations, information ServerInformation) map[string]Relationship {
+func getStructRelationships(relationer MarshalLinkedRelations, options *marshalOptions) map[string]Relationship {
 	referencedIDs := relationer.GetReferencedIDs()
 	sortedResults := map[string][]ReferenceID{}
 	relationships := map[string]Relationship{}
@@ -337,12 +364,12 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 		}
 
 		// set URLs if necessary
-		links := getLinksForServerInformation(relationer, name, information)
+		links := getLinksForServerInformation(relationer, name, options)
 
 		// get the custom meta for this relationship
 		var meta map[string]interface{}
 		if customMetaSource, ok := relationer.(MarshalCustomRelationshipMeta); ok {
-			meta = getMetaForRelation(customMetaSource, name, information)
+			meta = getMetaForRelation(customMetaSource, name, options.information)
 		}
 
 		relationship := Relationship{
@@ -366,12 +393,12 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 			container.DataArray = []RelationshipData{}
 		}
 
-		links := getLinksForServerInformation(relationer, name, information)
+		links := getLinksForServerInformation(relationer, name, options)
 
 		// get the custom meta for this relationship
 		var meta map[string]interface{}
 		if customMetaSource, ok := relationer.(MarshalCustomRelationshipMeta); ok {
-			meta = getMetaForRelation(customMetaSource, name, information)
+			meta = getMetaForRelation(customMetaSource, name, options.information)
 		}
 
 		relationship := Relationship{
@@ -402,13 +429,13 @@ func getLinkBaseURL(element MarshalIdentifier, information ServerInformation) st
 	return fmt.Sprintf("%s/%s/%s", prefix, structType, element.GetID())
 }
 
-func getLinksForServerInformation(relationer MarshalLinkedRelations, name string, information ServerInformation) Links {
-	if information == nil {
+func getLinksForServerInformation(relationer MarshalLinkedRelations, name string, options *marshalOptions) Links {
+	if !options.RelationshipLinks {
 		return nil
 	}
 
 	links := make(Links)
-	base := getLinkBaseURL(relationer, information)
+	base := getLinkBaseURL(relationer, options.information)
 
 	links["self"] = Link{Href: fmt.Sprintf("%s/relationships/%s", base, name)}
 	links["related"] = Link{Href: fmt.Sprintf("%s/%s", base, name)}
@@ -416,10 +443,10 @@ func getLinksForServerInformation(relationer MarshalLinkedRelations, name string
 	return links
 }
 
-func marshalStruct(data MarshalIdentifier, information ServerInformation) (*Document, error) {
+func marshalStruct(data MarshalIdentifier, options *marshalOptions) (*Document, error) {
 	var contentData Data
 
-	err := marshalData(data, &contentData, information)
+	err := marshalData(data, &contentData, options)
 	if err != nil {
 		return nil, err
 	}
@@ -430,16 +457,13 @@ func marshalStruct(data MarshalIdentifier, information ServerInformation) (*Docu
 		},
 	}
 
-	included, ok := data.(MarshalIncludedRelations)
-	if ok {
-		included, err := filterDuplicates(recursivelyEmbedIncludes(included.GetReferencedStructs()), information)
-		if err != nil {
-			return nil, err
-		}
+	included, err := options.included([]MarshalIdentifier{data})
+	if err != nil {
+		return nil, err
+	}
 
-		if len(included) > 0 {
-			result.Included = included
-		}
+	if len(included) > 0 {
+		result.Included = included
 	}
 
 	return result, nil
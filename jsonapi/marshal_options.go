@@ -0,0 +1,265 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MarshalOptions control how MarshalWithOptions renders a document. The zero
+// value renders the same document as Marshal.
+type MarshalOptions struct {
+	// BaseURL and Prefix are used for generated links, e.g.
+	// "https://example.com" and "v1" result in links like
+	// "https://example.com/v1/posts/1/relationships/comments".
+	BaseURL string
+	Prefix  string
+
+	// RelationshipLinks adds `self` and `related` links to all relationships.
+	RelationshipLinks bool
+
+	// CustomLinks adds the links of MarshalCustomLinks implementors.
+	CustomLinks bool
+
+	// Include limits the included structs to the given relationship paths,
+	// e.g. "author" or "comments.author". If Include is nil, all structs
+	// returned by GetReferencedStructs are included, if it is empty none are.
+	Include []string
+
+	// ResolveInclude is called for structs of include paths that are not
+	// returned by GetReferencedStructs. It returns nil if the struct does not
+	// exist. Referenced structs that are not loaded are skipped without it.
+	ResolveInclude func(resourceType, id string) (MarshalIdentifier, error)
+
+	// Fields only marshals the given fields of each type, e.g.
+	// {"posts": {"title"}}. Types without fields are marshalled completely,
+	// fields that do not exist are returned as an *InvalidFieldsError.
+	// https://jsonapi.org/format/#fetching-sparse-fieldsets
+	Fields map[string][]string
+
+	// Meta and Links are set as top-level meta and links of the document.
+	Meta  map[string]interface{}
+	Links Links
+}
+
+// MarshalWithOptions wraps data in a Document rendered with options and
+// returns its JSON encoding.
+func MarshalWithOptions(data interface{}, options MarshalOptions) ([]byte, error) {
+	document, err := MarshalToStructWithOptions(data, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(document)
+}
+
+// MarshalToStructWithOptions works like MarshalToStruct, but renders the
+// Document with options. If options contain fields that do not exist, the
+// Document is returned along with an *InvalidFieldsError with all of them.
+func MarshalToStructWithOptions(data interface{}, options MarshalOptions) (*Document, error) {
+	marshaller := newMarshalOptions(options)
+	document, err := marshalToStruct(data, marshaller)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(options.Meta) > 0 {
+		document.Meta = options.Meta
+	}
+
+	if len(options.Links) > 0 {
+		document.Links = options.Links
+	}
+
+	return document, marshaller.fields.err()
+}
+
+// serverOptions returns the options MarshalToStruct uses for information
+func serverOptions(information ServerInformation) MarshalOptions {
+	if information == nil {
+		return MarshalOptions{}
+	}
+
+	return MarshalOptions{
+		BaseURL:           information.GetBaseURL(),
+		Prefix:            information.GetPrefix(),
+		RelationshipLinks: true,
+		CustomLinks:       true,
+	}
+}
+
+// marshalOptions are the options of a single marshal call
+type marshalOptions struct {
+	MarshalOptions
+	information ServerInformation
+	fields      *fieldset
+}
+
+func newMarshalOptions(options MarshalOptions) *marshalOptions {
+	return &marshalOptions{
+		MarshalOptions: options,
+		information:    serverInformation{baseURL: options.BaseURL, prefix: options.Prefix},
+		fields:         newFieldset(options.Fields),
+	}
+}
+
+type serverInformation struct {
+	baseURL, prefix string
+}
+
+func (i serverInformation) GetBaseURL() string {
+	return i.baseURL
+}
+
+func (i serverInformation) GetPrefix() string {
+	return i.prefix
+}
+
+// included marshals the included structs of the primary data elements
+func (o *marshalOptions) included(elements []MarshalIdentifier) ([]Data, error) {
+	if o.Include == nil {
+		var referencedStructs []MarshalIdentifier
+		for _, element := range elements {
+			if included, ok := element.(MarshalIncludedRelations); ok {
+				referencedStructs = append(referencedStructs, included.GetReferencedStructs()...)
+			}
+		}
+
+		return filterDuplicates(recursivelyEmbedIncludes(referencedStructs), o)
+	}
+
+	resolver := includeResolver{
+		resolve: o.ResolveInclude,
+		loaded:  map[string]map[string]MarshalIdentifier{},
+	}
+
+	referencedStructs, err := resolver.walk(newIncludeTree(o.Include), elements)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterDuplicates(referencedStructs, o)
+}
+
+// includePath is one segment of an include path with the segments following it
+type includePath struct {
+	name     string
+	children []*includePath
+}
+
+func newIncludeTree(paths []string) []*includePath {
+	var tree []*includePath
+
+	for _, path := range paths {
+		nodes := &tree
+		for _, segment := range strings.Split(path, ".") {
+			var node *includePath
+			for _, candidate := range *nodes {
+				if candidate.name == segment {
+					node = candidate
+					break
+				}
+			}
+
+			if node == nil {
+				node = &includePath{name: segment}
+				*nodes = append(*nodes, node)
+			}
+			nodes = &node.children
+		}
+	}
+
+	return tree
+}
+
+type includeResolver struct {
+	resolve func(resourceType, id string) (MarshalIdentifier, error)
+	loaded  map[string]map[string]MarshalIdentifier
+}
+
+func (i *includeResolver) walk(nodes []*includePath, parents []MarshalIdentifier) ([]MarshalIdentifier, error) {
+	var result []MarshalIdentifier
+
+	for _, node := range nodes {
+		var children []MarshalIdentifier
+
+		for _, parent := range parents {
+			linked, ok := parent.(MarshalLinkedRelations)
+			if !ok {
+				continue
+			}
+
+			var preloaded []MarshalIdentifier
+			if included, ok := parent.(MarshalIncludedRelations); ok {
+				preloaded = included.GetReferencedStructs()
+			}
+
+			defaultType := ""
+			for _, reference := range linked.GetReferences() {
+				if reference.Name == node.name {
+					defaultType = reference.Type
+					break
+				}
+			}
+
+			for _, referenceID := range linked.GetReferencedIDs() {
+				if referenceID.Name != node.name {
+					continue
+				}
+
+				referenceType := referenceID.Type
+				if referenceType == "" {
+					referenceType = defaultType
+				}
+
+				child, err := i.load(referenceType, referenceID.ID, preloaded)
+				if err != nil {
+					return nil, err
+				}
+
+				if child != nil {
+					children = append(children, child)
+				}
+			}
+		}
+
+		result = append(result, children...)
+
+		if len(node.children) > 0 && len(children) > 0 {
+			nested, err := i.walk(node.children, children)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, nested...)
+		}
+	}
+
+	return result, nil
+}
+
+func (i *includeResolver) load(referenceType, id string, preloaded []MarshalIdentifier) (MarshalIdentifier, error) {
+	if obj, ok := i.loaded[referenceType][id]; ok {
+		return obj, nil
+	}
+
+	var obj MarshalIdentifier
+	for _, candidate := range preloaded {
+		if candidate.GetID() == id && getStructType(candidate) == referenceType {
+			obj = candidate
+			break
+		}
+	}
+
+	if obj == nil && i.resolve != nil {
+		var err error
+		if obj, err = i.resolve(referenceType, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.loaded[referenceType] == nil {
+		i.loaded[referenceType] = map[string]MarshalIdentifier{}
+	}
+	i.loaded[referenceType][id] = obj
+
+	return obj, nil
+}
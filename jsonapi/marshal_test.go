@@ -2,6 +2,8 @@ package jsonapi
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"time"
 
 	"gopkg.in/guregu/null.v3/zero"
@@ -1144,6 +1146,167 @@ var _ = Describe("Marshalling", func() {
 		})
 	})
 
+	Context("When marshalling with sparse fieldsets", func() {
+		posts := []SimplePost{{ID: "first", Title: "First Post", Text: "Lipsum", Size: 3}}
+
+		It("marshals only the requested attributes", func() {
+			document, err := MarshalToStructWithOptions(posts, MarshalOptions{Fields: map[string][]string{"simplePosts": {"size", "title"}}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Data.DataArray[0].Attributes).To(MatchJSON(`{"title": "First Post", "size": 3}`))
+		})
+
+		It("marshals all attributes of types without fields", func() {
+			document, err := MarshalToStructWithOptions(posts, MarshalOptions{Fields: map[string][]string{"users": {"name"}}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Data.DataArray[0].Attributes).To(MatchJSON(`{
+				"title": "First Post",
+				"text": "Lipsum",
+				"size": 3,
+				"created-date": "0001-01-01T00:00:00Z",
+				"updated-date": "0001-01-01T00:00:00Z"
+			}`))
+		})
+
+		It("treats relationships as fields", func() {
+			post := Post{ID: 1, Title: "Title", Comments: []Comment{{ID: 1}}, Author: &User{ID: 1}}
+			document, err := MarshalToStructWithOptions(post, MarshalOptions{Fields: map[string][]string{"posts": {"title", "comments"}}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Data.DataObject.Attributes).To(MatchJSON(`{"title": "Title"}`))
+			Expect(document.Data.DataObject.Relationships).To(HaveLen(1))
+			Expect(document.Data.DataObject.Relationships).To(HaveKey("comments"))
+		})
+
+		It("selects the fields of the output of encoding/json", func() {
+			type Base struct {
+				Name    string `json:"name"`
+				Hidden  string `json:"hidden"`
+				Ignored string `json:"-"`
+			}
+			type Timestamps struct {
+				Created time.Time `json:"created,omitzero"`
+			}
+			type Sparse struct {
+				Base
+				*Timestamps
+				ID     string `json:"-"`
+				Hidden int    `json:"hidden"`
+				Count  int    `json:"count,string"`
+				Note   string `json:"note,omitempty"`
+				Plain  bool
+			}
+
+			fields := []string{"name", "hidden", "created", "count", "note", "Plain"}
+			for _, element := range []Sparse{
+				{Base: Base{Name: "Name", Hidden: "shadowed"}, Timestamps: &Timestamps{}, Hidden: 2, Count: 3, Plain: true},
+				{Timestamps: &Timestamps{Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}, Note: "note"},
+			} {
+				all, err := json.Marshal(element)
+				Expect(err).ToNot(HaveOccurred())
+
+				selected, _, err := selectAttributes(all, fields)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(selected).To(MatchJSON(all))
+			}
+
+			all, err := json.Marshal(Sparse{Count: 3, Note: "note"})
+			Expect(err).ToNot(HaveOccurred())
+			selected, unknown, err := selectAttributes(all, []string{"note", "count", "Ignored", "ID"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(selected)).To(Equal(`{"count":"3","note":"note"}`))
+			Expect(unknown).To(Equal([]string{"Ignored", "ID"}))
+		})
+
+		It("returns all invalid fields", func() {
+			_, err := MarshalToStructWithOptions(posts, MarshalOptions{Fields: map[string][]string{"simplePosts": {"title", "author", "tags"}}})
+			invalidFields := &InvalidFieldsError{}
+			Expect(errors.As(err, &invalidFields)).To(BeTrue())
+			Expect(invalidFields.Fields).To(Equal(map[string][]string{"simplePosts": {"author", "tags"}}))
+			Expect(errors.Is(err, ErrRequestedInvalidFields)).To(BeTrue())
+			Expect(invalidFields.Errors()).To(HaveLen(2))
+		})
+	})
+
+	Context("When marshalling with options", func() {
+		post := Post{
+			ID:       1,
+			Title:    "Title",
+			Author:   &User{ID: 1, Name: "Dieter"},
+			Comments: []Comment{{ID: 1, Text: "First", SubComments: []Comment{{ID: 2, Text: "Reply"}}}},
+		}
+
+		It("marshals the same document as Marshal without options", func() {
+			expected, err := Marshal(post)
+			Expect(err).ToNot(HaveOccurred())
+			marshalled, err := MarshalWithOptions(post, MarshalOptions{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(marshalled).To(MatchJSON(expected))
+		})
+
+		It("toggles relationship and custom links", func() {
+			document, err := MarshalToStructWithOptions(post, MarshalOptions{BaseURL: "http://my.domain", Prefix: "v1", RelationshipLinks: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Data.DataObject.Relationships["author"].Links).To(Equal(Links{
+				"self":    Link{Href: "http://my.domain/v1/posts/1/relationships/author"},
+				"related": Link{Href: "http://my.domain/v1/posts/1/author"},
+			}))
+
+			document, err = MarshalToStructWithOptions(CustomLinksPost{}, MarshalOptions{BaseURL: "http://my.domain", CustomLinks: true})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Data.DataObject.Links["someLink"]).To(Equal(Link{Href: "http://my.domain/posts/someID/someLink"}))
+
+			document, err = MarshalToStructWithOptions(CustomLinksPost{}, MarshalOptions{BaseURL: "http://my.domain"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Data.DataObject.Links).To(BeEmpty())
+		})
+
+		It("includes only the requested relationship paths", func() {
+			document, err := MarshalToStructWithOptions(post, MarshalOptions{Include: []string{"author"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Included).To(HaveLen(1))
+			Expect(document.Included[0].Type).To(Equal("users"))
+
+			document, err = MarshalToStructWithOptions([]Post{post}, MarshalOptions{Include: []string{"comments.comments"}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Included).To(HaveLen(2))
+			Expect(document.Included[0].ID).To(Equal("1"))
+			Expect(document.Included[1].ID).To(Equal("2"))
+
+			document, err = MarshalToStructWithOptions(post, MarshalOptions{Include: []string{}})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(document.Included).To(BeEmpty())
+		})
+
+		It("resolves included structs that are not loaded", func() {
+			var resolved []string
+			options := MarshalOptions{
+				Include: []string{"comments"},
+				ResolveInclude: func(resourceType, id string) (MarshalIdentifier, error) {
+					resolved = append(resolved, resourceType+"/"+id)
+					return Comment{ID: 3, Text: "Loaded"}, nil
+				},
+			}
+
+			document, err := MarshalToStructWithOptions(Post{ID: 2, CommentsIDs: []int{3}}, options)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(resolved).To(Equal([]string{"comments/3"}))
+			Expect(document.Included).To(HaveLen(1))
+			Expect(document.Included[0].Attributes).To(MatchJSON(`{"text": "Loaded"}`))
+		})
+
+		It("adds top-level meta and links", func() {
+			marshalled, err := MarshalWithOptions([]SimplePost{}, MarshalOptions{
+				Meta:  map[string]interface{}{"total": 0},
+				Links: Links{"self": Link{Href: "/simplePosts"}},
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(marshalled).To(MatchJSON(`{
+				"links": {"self": "/simplePosts"},
+				"data": [],
+				"meta": {"total": 0}
+			}`))
+		})
+	})
+
 	Context("Slice fields", func() {
 		It("Marshalls the slice field correctly", func() {
 			marshalled, err := Marshal(Identity{1234, []string{"user_global"}})
@@ -1211,7 +1374,7 @@ var _ = Describe("Marshalling", func() {
 		})
 
 		It("Generates to-one relationships correctly", func() {
-			links := getStructRelationships(post, nil)
+			links := getStructRelationships(post, newMarshalOptions(MarshalOptions{}))
 			Expect(links["author"]).To(Equal(Relationship{
 				Data: &RelationshipDataContainer{
 					DataObject: &RelationshipData{
@@ -1223,7 +1386,7 @@ var _ = Describe("Marshalling", func() {
 		})
 
 		It("Generates to-many relationships correctly", func() {
-			links := getStructRelationships(post, nil)
+			links := getStructRelationships(post, newMarshalOptions(MarshalOptions{}))
 			Expect(links["comments"]).To(Equal(Relationship{
 				Data: &RelationshipDataContainer{
 					DataArray: []RelationshipData{
@@ -1237,7 +1400,7 @@ var _ = Describe("Marshalling", func() {
 		})
 
 		It("Generates self/related URLs with baseURL and prefix correctly", func() {
-			links := getStructRelationships(post, CompleteServerInformation{})
+			links := getStructRelationships(post, newMarshalOptions(serverOptions(CompleteServerInformation{})))
 			Expect(links["author"]).To(Equal(Relationship{
 				Data: &RelationshipDataContainer{
 					DataObject: &RelationshipData{
@@ -1253,7 +1416,7 @@ var _ = Describe("Marshalling", func() {
 		})
 
 		It("Generates self/related URLs with baseURL correctly", func() {
-			links := getStructRelationships(post, BaseURLServerInformation{})
+			links := getStructRelationships(post, newMarshalOptions(serverOptions(BaseURLServerInformation{})))
 			Expect(links["author"]).To(Equal(Relationship{
 				Data: &RelationshipDataContainer{
 					DataObject: &RelationshipData{
@@ -1269,7 +1432,7 @@ var _ = Describe("Marshalling", func() {
 		})
 
 		It("Generates self/related URLs with prefix correctly", func() {
-			links := getStructRelationships(post, PrefixServerInformation{})
+			links := getStructRelationships(post, newMarshalOptions(serverOptions(PrefixServerInformation{})))
 			Expect(links["author"]).To(Equal(Relationship{
 				Data: &RelationshipDataContainer{
 					DataObject: &RelationshipData{
@@ -1307,7 +1470,7 @@ var _ = Describe("Marshalling", func() {
 		}
 
 		It("should work with default marshalData", func() {
-			actual, err := filterDuplicates(input, nil)
+			actual, err := filterDuplicates(input, newMarshalOptions(MarshalOptions{}))
 			Expect(err).ToNot(HaveOccurred())
 			Expect(len(actual)).To(Equal(len(expected)))
 		})
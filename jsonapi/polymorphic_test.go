@@ -0,0 +1,131 @@
+package jsonapi
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type Activity struct {
+	ID      string        `json:"-"`
+	Action  string        `json:"action"`
+	Subject ReferenceID   `json:"-"`
+	Targets []ReferenceID `json:"-"`
+}
+
+func (a Activity) GetID() string {
+	return a.ID
+}
+
+func (a *Activity) SetID(ID string) error {
+	a.ID = ID
+	return nil
+}
+
+func (a Activity) GetReferences() []Reference {
+	return []Reference{
+		{Types: []string{"users", "groups"}, Name: "subject", Relationship: ToOneRelationship},
+		{Types: []string{"posts", "comments"}, Name: "targets", Relationship: ToManyRelationship},
+	}
+}
+
+func (a Activity) GetReferencedIDs() []ReferenceID {
+	result := []ReferenceID{}
+	if a.Subject.ID != "" {
+		result = append(result, a.Subject)
+	}
+
+	return append(result, a.Targets...)
+}
+
+func (a *Activity) SetToOneReference(name string, reference ReferenceID) error {
+	a.Subject = reference
+	return nil
+}
+
+func (a *Activity) SetToManyReferences(name string, references []ReferenceID) error {
+	a.Targets = references
+	return nil
+}
+
+var _ = Describe("Polymorphic relationships", func() {
+	It("marshals the type of every referenced resource", func() {
+		activity := Activity{
+			ID:      "1",
+			Action:  "liked",
+			Subject: ReferenceID{ID: "2", Type: "groups", Name: "subject", Relationship: ToOneRelationship},
+			Targets: []ReferenceID{
+				{ID: "3", Type: "posts", Name: "targets", Relationship: ToManyRelationship},
+				{ID: "4", Type: "comments", Name: "targets", Relationship: ToManyRelationship},
+			},
+		}
+
+		result, err := Marshal(activity)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(MatchJSON(`{"data": {
+			"type": "activities",
+			"id": "1",
+			"attributes": {"action": "liked"},
+			"relationships": {
+				"subject": {"data": {"type": "groups", "id": "2"}},
+				"targets": {"data": [{"type": "posts", "id": "3"}, {"type": "comments", "id": "4"}]}
+			}
+		}}`))
+	})
+
+	It("unmarshals the type of every referenced resource", func() {
+		activity := Activity{}
+		err := Unmarshal([]byte(`{"data": {
+			"type": "activities",
+			"id": "1",
+			"attributes": {"action": "liked"},
+			"relationships": {
+				"subject": {"data": {"type": "users", "id": "2"}},
+				"targets": {"data": [{"type": "comments", "id": "4"}, {"type": "posts", "id": "3"}]}
+			}
+		}}`), &activity)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(activity.Subject).To(Equal(ReferenceID{ID: "2", Type: "users", Name: "subject", Relationship: ToOneRelationship}))
+		Expect(activity.Targets).To(Equal([]ReferenceID{
+			{ID: "4", Type: "comments", Name: "targets", Relationship: ToManyRelationship},
+			{ID: "3", Type: "posts", Name: "targets", Relationship: ToManyRelationship},
+		}))
+
+		err = Unmarshal([]byte(`{"data": {"type": "activities", "id": "1", "relationships": {"subject": {"data": null}}}}`), &activity)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(activity.Subject).To(Equal(ReferenceID{Name: "subject", Relationship: ToOneRelationship}))
+	})
+
+	It("keeps the types in the linkage of resources", func() {
+		schema := &Schema{
+			Type: "activities",
+			Relationships: []Reference{
+				{Types: []string{"users", "groups"}, Name: "subject", Relationship: ToOneRelationship},
+			},
+		}
+
+		activity := schema.NewResource("1")
+		err := Unmarshal([]byte(`{"data": {"type": "activities", "relationships": {"subject": {"data": {"type": "groups", "id": "2"}}}}}`), activity)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(activity.GetReferencedIDs()).To(Equal([]ReferenceID{
+			{ID: "2", Type: "groups", Name: "subject", Relationship: ToOneRelationship},
+		}))
+
+		err = Unmarshal([]byte(`{"data": {"type": "activities", "relationships": {"subject": {"data": {"type": "posts", "id": "2"}}}}}`), activity)
+		Expect(err).To(MatchError(ContainSubstring("Relationship subject can not reference resources of type posts")))
+
+		Expect(activity.SetToManyReferences("subject", nil)).ToNot(Succeed())
+
+		schema.Relationships = append(schema.Relationships, Reference{Types: []string{"users", "groups"}, Name: "members", Relationship: ToManyRelationship})
+		Expect(activity.AddToManyReferences("members", []ReferenceID{{ID: "1", Type: "users"}, {ID: "1", Type: "groups"}})).To(Succeed())
+		Expect(activity.DeleteToManyReferences("members", []ReferenceID{{ID: "1", Type: "users"}})).To(Succeed())
+		Expect(activity.Relationships["members"].DataArray).To(Equal([]RelationshipData{{Type: "groups", ID: "1"}}))
+		Expect(activity.AddToManyReferences("members", []ReferenceID{{ID: "1", Type: "posts"}})).ToNot(Succeed())
+
+		generic := Resource{Type: "activities", ID: "1", Relationships: map[string]RelationshipDataContainer{
+			"targets": {DataArray: []RelationshipData{{Type: "posts", ID: "3"}, {Type: "comments", ID: "4"}}},
+		}}
+		Expect(generic.GetReferences()).To(Equal([]Reference{
+			{Types: []string{"posts", "comments"}, Name: "targets", Relationship: ToManyRelationship},
+		}))
+	})
+})
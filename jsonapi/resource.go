@@ -0,0 +1,429 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// AttributeType is the JSON type of an attribute of a Schema
+type AttributeType string
+
+// The available attribute types, AnyAttribute accepts all values.
+const (
+	AnyAttribute     AttributeType = ""
+	StringAttribute  AttributeType = "string"
+	NumberAttribute  AttributeType = "number"
+	BooleanAttribute AttributeType = "boolean"
+	ObjectAttribute  AttributeType = "object"
+	ArrayAttribute   AttributeType = "array"
+)
+
+// AttributeDefinition describes an attribute of a Schema
+type AttributeDefinition struct {
+	Name     string
+	Type     AttributeType
+	Required bool
+}
+
+// Schema describes a resource type that has no Go struct, e.g. a type that
+// is configured at runtime. Its resources are represented by Resource values.
+type Schema struct {
+	Type          string
+	Attributes    []AttributeDefinition
+	Relationships []Reference
+}
+
+// NewResource returns an empty resource of the schema
+func (s *Schema) NewResource(ID string) *Resource {
+	return &Resource{
+		Type:          s.Type,
+		ID:            ID,
+		Attributes:    map[string]interface{}{},
+		Relationships: map[string]RelationshipDataContainer{},
+		Schema:        s,
+	}
+}
+
+func (s *Schema) attribute(name string) (AttributeDefinition, bool) {
+	for _, attribute := range s.Attributes {
+		if attribute.Name == name {
+			return attribute, true
+		}
+	}
+
+	return AttributeDefinition{}, false
+}
+
+func (s *Schema) relationship(name string) (Reference, bool) {
+	for _, reference := range s.Relationships {
+		if reference.Name == name {
+			return reference, true
+		}
+	}
+
+	return Reference{}, false
+}
+
+// Resource is a generic resource object that Marshal and Unmarshal handle
+// like a struct. Its relationships contain the linkage of each relationship,
+// DataArray is set for to-many relationships.
+//
+// Resources with a Schema marshal all attributes and relationships of the
+// schema, Unmarshal rejects attributes and relationships that are not part of
+// it. Unmarshal sets the type of resources without a type.
+type Resource struct {
+	Type          string
+	ID            string
+	Attributes    map[string]interface{}
+	Relationships map[string]RelationshipDataContainer
+	Schema        *Schema
+}
+
+// GetID returns the id of the resource
+func (r Resource) GetID() string {
+	return r.ID
+}
+
+// SetID sets the id of the resource
+func (r *Resource) SetID(ID string) error {
+	r.ID = ID
+	return nil
+}
+
+// GetName returns the type of the resource
+func (r Resource) GetName() string {
+	return r.Type
+}
+
+// MarshalJSON returns the attributes of the resource. Attributes of the schema
+// that are not set are null.
+func (r Resource) MarshalJSON() ([]byte, error) {
+	attributes := map[string]interface{}{}
+	if r.Schema != nil {
+		for _, attribute := range r.Schema.Attributes {
+			attributes[attribute.Name] = nil
+		}
+	}
+
+	for name, value := range r.Attributes {
+		attributes[name] = value
+	}
+
+	return json.Marshal(attributes)
+}
+
+// GetReferences returns the relationships of the schema or, without a schema,
+// of the linkage of the resource
+func (r Resource) GetReferences() []Reference {
+	if r.Schema != nil {
+		return r.Schema.Relationships
+	}
+
+	names := make([]string, 0, len(r.Relationships))
+	for name := range r.Relationships {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	references := make([]Reference, len(names))
+	for i, name := range names {
+		linkage := r.Relationships[name]
+		references[i] = Reference{Name: name, Relationship: ToOneRelationship}
+		if linkage.DataArray != nil {
+			references[i].Relationship = ToManyRelationship
+		}
+
+		var types []string
+		for _, data := range linkage.entries() {
+			if !contains(types, data.Type) {
+				types = append(types, data.Type)
+			}
+		}
+
+		switch {
+		case len(types) == 1:
+			references[i].Type = types[0]
+		case len(types) > 1:
+			references[i].Types = types
+		}
+	}
+
+	return references
+}
+
+// GetReferencedIDs returns the linkage of all relationships
+func (r Resource) GetReferencedIDs() []ReferenceID {
+	result := []ReferenceID{}
+
+	for _, reference := range r.GetReferences() {
+		linkage := r.Relationships[reference.Name]
+		relationship := ToOneRelationship
+		if linkage.DataArray != nil {
+			relationship = ToManyRelationship
+		}
+
+		for _, data := range linkage.entries() {
+			referenceType := data.Type
+			if referenceType == "" {
+				referenceType = reference.Type
+			}
+
+			result = append(result, ReferenceID{
+				ID:           data.ID,
+				Type:         referenceType,
+				Name:         reference.Name,
+				Relationship: relationship,
+			})
+		}
+	}
+
+	return result
+}
+
+// SetToOneReferenceID sets the linkage of a to-one relationship, an empty ID
+// removes it
+func (r *Resource) SetToOneReferenceID(name, ID string) error {
+	linkage := RelationshipDataContainer{}
+	if ID != "" {
+		linkage.DataObject = &RelationshipData{Type: r.referenceType(name), ID: ID}
+	}
+
+	return r.setLinkage(name, linkage)
+}
+
+// SetToManyReferenceIDs replaces the linkage of a to-many relationship
+func (r *Resource) SetToManyReferenceIDs(name string, IDs []string) error {
+	linkage := RelationshipDataContainer{DataArray: []RelationshipData{}}
+	for _, ID := range IDs {
+		linkage.DataArray = append(linkage.DataArray, RelationshipData{Type: r.referenceType(name), ID: ID})
+	}
+
+	return r.setLinkage(name, linkage)
+}
+
+// SetToOneReference sets the linkage of a to-one relationship with the type of
+// the reference, an empty ID removes it
+func (r *Resource) SetToOneReference(name string, reference ReferenceID) error {
+	linkage := RelationshipDataContainer{}
+	if reference.ID != "" {
+		linkage.DataObject = &RelationshipData{Type: reference.Type, ID: reference.ID}
+	}
+
+	return r.setLinkage(name, linkage)
+}
+
+// SetToManyReferences replaces the linkage of a to-many relationship with the
+// types of the references
+func (r *Resource) SetToManyReferences(name string, references []ReferenceID) error {
+	linkage := RelationshipDataContainer{DataArray: []RelationshipData{}}
+	for _, reference := range references {
+		linkage.DataArray = append(linkage.DataArray, RelationshipData{Type: reference.Type, ID: reference.ID})
+	}
+
+	return r.setLinkage(name, linkage)
+}
+
+// AddToManyIDs adds IDs to the linkage of a to-many relationship
+func (r *Resource) AddToManyIDs(name string, IDs []string) error {
+	linkage := RelationshipDataContainer{DataArray: []RelationshipData{}}
+	linkage.DataArray = append(linkage.DataArray, r.Relationships[name].DataArray...)
+
+	for _, ID := range IDs {
+		linkage.DataArray = append(linkage.DataArray, RelationshipData{Type: r.referenceType(name), ID: ID})
+	}
+
+	return r.setLinkage(name, linkage)
+}
+
+// DeleteToManyIDs removes IDs from the linkage of a to-many relationship
+func (r *Resource) DeleteToManyIDs(name string, IDs []string) error {
+	linkage := RelationshipDataContainer{DataArray: []RelationshipData{}}
+	for _, data := range r.Relationships[name].DataArray {
+		if !contains(IDs, data.ID) {
+			linkage.DataArray = append(linkage.DataArray, data)
+		}
+	}
+
+	return r.setLinkage(name, linkage)
+}
+
+// AddToManyReferences adds references to the linkage of a to-many
+// relationship and keeps their types
+func (r *Resource) AddToManyReferences(name string, references []ReferenceID) error {
+	linkage := RelationshipDataContainer{DataArray: []RelationshipData{}}
+	linkage.DataArray = append(linkage.DataArray, r.Relationships[name].DataArray...)
+
+	for _, reference := range references {
+		linkage.DataArray = append(linkage.DataArray, RelationshipData{Type: reference.Type, ID: reference.ID})
+	}
+
+	return r.setLinkage(name, linkage)
+}
+
+// DeleteToManyReferences removes references with the same type and id from
+// the linkage of a to-many relationship
+func (r *Resource) DeleteToManyReferences(name string, references []ReferenceID) error {
+	linkage := RelationshipDataContainer{DataArray: []RelationshipData{}}
+	for _, data := range r.Relationships[name].DataArray {
+		deleted := false
+		for _, reference := range references {
+			deleted = deleted || (reference.ID == data.ID && reference.Type == data.Type)
+		}
+
+		if !deleted {
+			linkage.DataArray = append(linkage.DataArray, data)
+		}
+	}
+
+	return r.setLinkage(name, linkage)
+}
+
+// referenceType returns the type of the resources of a relationship, it is
+// empty for polymorphic relationships without linkage
+func (r *Resource) referenceType(name string) string {
+	if r.Schema != nil {
+		if reference, ok := r.Schema.relationship(name); ok {
+			return reference.Type
+		}
+	}
+
+	if data := r.Relationships[name].entries(); len(data) > 0 {
+		return data[0].Type
+	}
+
+	return ""
+}
+
+// setLinkage sets the linkage of a relationship, relationships that are not
+// part of the schema and linkage of the wrong kind are rejected
+func (r *Resource) setLinkage(name string, linkage RelationshipDataContainer) error {
+	if r.Schema != nil {
+		reference, ok := r.Schema.relationship(name)
+		if !ok {
+			return fmt.Errorf("There is no relationship named %s", name)
+		}
+
+		if toMany := isToMany(reference.Relationship, reference.Name); toMany != (linkage.DataArray != nil) {
+			return fmt.Errorf("Linkage of relationship %s must be %s", name, linkageKind(toMany))
+		}
+
+		for _, data := range linkage.entries() {
+			if !reference.allows(data.Type) {
+				return fmt.Errorf("Relationship %s can not reference resources of type %s", name, data.Type)
+			}
+		}
+	}
+
+	if r.Relationships == nil {
+		r.Relationships = map[string]RelationshipDataContainer{}
+	}
+	r.Relationships[name] = linkage
+
+	return nil
+}
+
+// setAttributes merges the attributes into the resource, pointer is the JSON
+// pointer to the resource object used in errors
+func (r *Resource) setAttributes(payload json.RawMessage, pointer string) error {
+	attributes := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &attributes); err != nil {
+		return err
+	}
+
+	if r.Schema != nil {
+		for name, value := range attributes {
+			attribute, ok := r.Schema.attribute(name)
+			if !ok {
+				return &UnmarshalError{
+					Err:     ErrInvalidDocument,
+					Pointer: pointer + "/attributes/" + name,
+					Detail:  fmt.Sprintf("There is no attribute named %s", name),
+				}
+			}
+
+			if !attribute.Type.matches(value) {
+				return &UnmarshalError{
+					Err:     ErrInvalidDocument,
+					Pointer: pointer + "/attributes/" + name,
+					Detail:  fmt.Sprintf("Attribute %s must be of type %s", name, attribute.Type),
+				}
+			}
+		}
+	}
+
+	if r.Attributes == nil {
+		r.Attributes = map[string]interface{}{}
+	}
+	for name, value := range attributes {
+		r.Attributes[name] = value
+	}
+
+	return nil
+}
+
+// setRelationships sets the linkage of all relationships, it keeps the type of
+// every resource identifier object
+func (r *Resource) setRelationships(relationships map[string]Relationship, pointer string) error {
+	for name, relationship := range relationships {
+		linkage := RelationshipDataContainer{}
+		if relationship.Data != nil {
+			linkage = *relationship.Data
+		}
+
+		if err := r.setLinkage(name, linkage); err != nil {
+			return &UnmarshalError{
+				Err:     ErrInvalidRelationship,
+				Pointer: pointer + "/relationships/" + name,
+				Detail:  err.Error(),
+			}
+		}
+	}
+
+	return nil
+}
+
+// matches reports whether a decoded JSON value has the type, null matches
+// all types
+func (t AttributeType) matches(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+
+	switch t {
+	case StringAttribute:
+		_, ok := value.(string)
+		return ok
+	case NumberAttribute:
+		_, ok := value.(float64)
+		return ok
+	case BooleanAttribute:
+		_, ok := value.(bool)
+		return ok
+	case ObjectAttribute:
+		_, ok := value.(map[string]interface{})
+		return ok
+	case ArrayAttribute:
+		_, ok := value.([]interface{})
+		return ok
+	}
+
+	return true
+}
+
+// entries returns the resource identifier objects of the linkage
+func (c RelationshipDataContainer) entries() []RelationshipData {
+	if c.DataObject != nil {
+		return []RelationshipData{*c.DataObject}
+	}
+
+	return c.DataArray
+}
+
+func linkageKind(toMany bool) string {
+	if toMany {
+		return "an array"
+	}
+
+	return "an object or null"
+}
@@ -0,0 +1,120 @@
+package jsonapi
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Resource", func() {
+	var schema *Schema
+
+	BeforeEach(func() {
+		schema = &Schema{
+			Type: "tickets",
+			Attributes: []AttributeDefinition{
+				{Name: "title", Type: StringAttribute, Required: true},
+				{Name: "priority", Type: NumberAttribute},
+				{Name: "labels"},
+			},
+			Relationships: []Reference{
+				{Type: "users", Name: "assignee", Relationship: ToOneRelationship},
+				{Type: "comments", Name: "comments", Relationship: ToManyRelationship},
+			},
+		}
+	})
+
+	It("marshals all attributes and relationships of the schema", func() {
+		ticket := schema.NewResource("1")
+		ticket.Attributes["title"] = "Broken"
+		ticket.Relationships["assignee"] = RelationshipDataContainer{DataObject: &RelationshipData{Type: "users", ID: "2"}}
+
+		marshalled, err := Marshal(ticket)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(marshalled).To(MatchJSON(`{"data": {
+			"type": "tickets",
+			"id": "1",
+			"attributes": {"title": "Broken", "priority": null, "labels": null},
+			"relationships": {
+				"assignee": {"data": {"type": "users", "id": "2"}},
+				"comments": {"data": []}
+			}
+		}}`))
+	})
+
+	It("unmarshals attributes and linkage into a resource of the schema", func() {
+		ticket := schema.NewResource("1")
+		ticket.Attributes["priority"] = 2.0
+
+		err := Unmarshal([]byte(`{"data": {
+			"type": "tickets",
+			"id": "1",
+			"attributes": {"title": "Broken", "labels": ["bug"]},
+			"relationships": {"comments": {"data": [{"type": "comments", "id": "3"}]}}
+		}}`), ticket)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ticket.Attributes).To(Equal(map[string]interface{}{
+			"title":    "Broken",
+			"priority": 2.0,
+			"labels":   []interface{}{"bug"},
+		}))
+		Expect(ticket.GetReferencedIDs()).To(Equal([]ReferenceID{
+			{ID: "3", Type: "comments", Name: "comments", Relationship: ToManyRelationship},
+		}))
+	})
+
+	It("rejects attributes and relationships that do not match the schema", func() {
+		err := Unmarshal([]byte(`{"data": {"type": "tickets", "attributes": {"state": "open"}}}`), schema.NewResource(""))
+		unmarshalError := &UnmarshalError{}
+		Expect(errors.As(err, &unmarshalError)).To(BeTrue())
+		Expect(unmarshalError.Pointer).To(Equal("/data/attributes/state"))
+
+		err = Unmarshal([]byte(`{"data": {"type": "tickets", "attributes": {"priority": "high"}}}`), schema.NewResource(""))
+		Expect(errors.As(err, &unmarshalError)).To(BeTrue())
+		Expect(unmarshalError.Detail).To(Equal("Attribute priority must be of type number"))
+
+		err = Unmarshal([]byte(`{"data": {"type": "tickets", "relationships": {"assignee": {"data": []}}}}`), schema.NewResource(""))
+		Expect(errors.Is(err, ErrInvalidRelationship)).To(BeTrue())
+		Expect(errors.As(err, &unmarshalError)).To(BeTrue())
+		Expect(unmarshalError.Pointer).To(Equal("/data/relationships/assignee"))
+
+		err = Unmarshal([]byte(`{"data": {"type": "posts"}}`), schema.NewResource(""))
+		Expect(errors.Is(err, ErrTypeMismatch)).To(BeTrue())
+	})
+
+	It("unmarshals resources without a schema", func() {
+		var resources []Resource
+		err := Unmarshal([]byte(`{"data": [{
+			"type": "things",
+			"id": "1",
+			"attributes": {"name": "Thing"},
+			"relationships": {"owner": {"data": {"type": "users", "id": "2"}}}
+		}]}`), &resources)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(resources).To(HaveLen(1))
+		Expect(resources[0].Type).To(Equal("things"))
+		Expect(resources[0].Attributes).To(Equal(map[string]interface{}{"name": "Thing"}))
+
+		marshalled, err := Marshal(resources)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(marshalled).To(MatchJSON(`{"data": [{
+			"type": "things",
+			"id": "1",
+			"attributes": {"name": "Thing"},
+			"relationships": {"owner": {"data": {"type": "users", "id": "2"}}}
+		}]}`))
+	})
+
+	It("edits to-many linkage", func() {
+		ticket := schema.NewResource("1")
+		Expect(ticket.SetToManyReferenceIDs("comments", []string{"1", "2"})).To(Succeed())
+		Expect(ticket.AddToManyIDs("comments", []string{"3"})).To(Succeed())
+		Expect(ticket.DeleteToManyIDs("comments", []string{"1"})).To(Succeed())
+		Expect(ticket.Relationships["comments"].DataArray).To(Equal([]RelationshipData{
+			{Type: "comments", ID: "2"},
+			{Type: "comments", ID: "3"},
+		}))
+		Expect(ticket.SetToOneReferenceID("comments", "1")).ToNot(Succeed())
+	})
+})
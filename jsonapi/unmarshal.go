@@ -5,8 +5,40 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 )
 
+// The kinds of an UnmarshalError, they can be checked with errors.Is
+var (
+	// ErrInvalidDocument is returned for documents that are no valid JSON:API documents
+	ErrInvalidDocument = errors.New("invalid document")
+	// ErrMissingData is returned if a required member like "data" or "type" is missing
+	ErrMissingData = errors.New("missing data")
+	// ErrTypeMismatch is returned if the type of a resource object does not match the target
+	ErrTypeMismatch = errors.New("type mismatch")
+	// ErrInvalidRelationship is returned for relationships the target cannot be set to
+	ErrInvalidRelationship = errors.New("invalid relationship")
+)
+
+// UnmarshalError is returned by Unmarshal if the document is invalid. Pointer
+// is the JSON pointer to the invalid member, e.g. "/data/type". Attributes
+// that cannot be unmarshalled return the error of encoding/json instead.
+type UnmarshalError struct {
+	Err     error
+	Pointer string
+	Detail  string
+}
+
+// Error returns the detail of the error
+func (e *UnmarshalError) Error() string {
+	return e.Detail
+}
+
+// Unwrap returns the kind of the error, e.g. ErrTypeMismatch
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
 // The UnmarshalIdentifier interface must be implemented to set the ID during
 // unmarshalling.
 type UnmarshalIdentifier interface {
@@ -25,6 +57,19 @@ type UnmarshalToManyRelations interface {
 	SetToManyReferenceIDs(name string, IDs []string) error
 }
 
+// The UnmarshalPolymorphicToOneRelations interface can be implemented instead
+// of UnmarshalToOneRelations to receive the type of the referenced resource,
+// e.g. for polymorphic relationships. The ID is empty for empty relationships.
+type UnmarshalPolymorphicToOneRelations interface {
+	SetToOneReference(name string, reference ReferenceID) error
+}
+
+// The UnmarshalPolymorphicToManyRelations interface can be implemented instead
+// of UnmarshalToManyRelations to receive the type of each referenced resource.
+type UnmarshalPolymorphicToManyRelations interface {
+	SetToManyReferences(name string, references []ReferenceID) error
+}
+
 // The UnmarshalResourceMeta interface must be implemented to unmarshal meta fields inside of data containers
 type UnmarshalResourceMeta interface {
 	MarshalIdentifier
@@ -87,6 +132,14 @@ type EditToManyRelations interface {
 	DeleteToManyIDs(name string, IDs []string) error
 }
 
+// The EditPolymorphicToManyRelations interface can be implemented instead of
+// EditToManyRelations to receive the type of each added or deleted resource,
+// e.g. for polymorphic relationships.
+type EditPolymorphicToManyRelations interface {
+	AddToManyReferences(name string, references []ReferenceID) error
+	DeleteToManyReferences(name string, references []ReferenceID) error
+}
+
 // Unmarshal parses a JSON API compatible JSON and populates the target which
 // must implement the `UnmarshalIdentifier` interface.
 func Unmarshal(data []byte, target interface{}) error {
@@ -102,15 +155,15 @@ func Unmarshal(data []byte, target interface{}) error {
 
 	err := json.Unmarshal(data, ctx)
 	if err != nil {
-		return err
+		return &UnmarshalError{Err: ErrInvalidDocument, Detail: err.Error()}
 	}
 
 	if ctx.Data == nil {
-		return errors.New(`Source JSON is empty and has no "attributes" payload object`)
+		return &UnmarshalError{Err: ErrMissingData, Pointer: "/data", Detail: `Source JSON is empty and has no "attributes" payload object`}
 	}
 
 	if ctx.Data.DataObject != nil {
-		err := setDataIntoTarget(ctx.Data.DataObject, target)
+		err := setDataIntoTarget(ctx.Data.DataObject, target, "/data")
 		if err != nil {
 			return err
 		}
@@ -120,13 +173,18 @@ func Unmarshal(data []byte, target interface{}) error {
 	if ctx.Data.DataArray != nil {
 		targetSlice := reflect.TypeOf(target).Elem()
 		if targetSlice.Kind() != reflect.Slice {
-			return fmt.Errorf("Cannot unmarshal array to struct target %s", targetSlice)
+			return &UnmarshalError{
+				Err:     ErrInvalidDocument,
+				Pointer: "/data",
+				Detail:  fmt.Sprintf("Cannot unmarshal array to struct target %s", targetSlice),
+			}
 		}
 		targetType := targetSlice.Elem()
 		targetPointer := reflect.ValueOf(target)
 		targetValue := targetPointer.Elem()
 
-		for _, record := range ctx.Data.DataArray {
+		for index, record := range ctx.Data.DataArray {
+			pointer := "/data/" + strconv.Itoa(index)
 			// check if there already is an entry with the same id in target slice,
 			// otherwise create a new target and append
 			var targetRecord, emptyValue reflect.Value
@@ -143,7 +201,7 @@ func Unmarshal(data []byte, target interface{}) error {
 
 			if targetRecord == emptyValue || targetRecord.IsNil() {
 				targetRecord = reflect.New(targetType)
-				err := setDataIntoTarget(&record, targetRecord.Interface())
+				err := setDataIntoTarget(&record, targetRecord.Interface(), pointer)
 				if err != nil {
 					return err
 				}
@@ -153,7 +211,7 @@ func Unmarshal(data []byte, target interface{}) error {
 				}
 				targetValue = reflect.Append(targetValue, targetRecord.Elem())
 			} else {
-				err := setDataIntoTarget(&record, targetRecord.Interface())
+				err := setDataIntoTarget(&record, targetRecord.Interface(), pointer)
 				if err != nil {
 					return err
 				}
@@ -195,23 +253,34 @@ func setIncludedIntoTarget(included []Data, target interface{}) error {
 	return nil
 }
 
-func setDataIntoTarget(data *Data, target interface{}) error {
+// setDataIntoTarget sets the resource object data into target, pointer is the
+// JSON pointer to data used in errors
+func setDataIntoTarget(data *Data, target interface{}, pointer string) error {
 	castedTarget, ok := target.(UnmarshalIdentifier)
 	if !ok {
 		return errors.New("target must implement UnmarshalIdentifier interface")
 	}
 
 	if data.Type == "" {
-		return errors.New("invalid record, no type was specified")
+		return &UnmarshalError{Err: ErrMissingData, Pointer: pointer + "/type", Detail: "invalid record, no type was specified"}
+	}
+
+	resource, isResource := target.(*Resource)
+	if isResource && resource.Type == "" {
+		resource.Type = data.Type
 	}
 
 	err := checkType(data.Type, castedTarget)
 	if err != nil {
-		return err
+		return &UnmarshalError{Err: ErrTypeMismatch, Pointer: pointer + "/type", Detail: err.Error()}
 	}
 
 	if data.Attributes != nil {
-		err = json.Unmarshal(data.Attributes, castedTarget)
+		if isResource {
+			err = resource.setAttributes(data.Attributes, pointer)
+		} else {
+			err = json.Unmarshal(data.Attributes, castedTarget)
+		}
 		if err != nil {
 			return err
 		}
@@ -230,59 +299,86 @@ func setDataIntoTarget(data *Data, target interface{}) error {
 		}
 	}
 
-	return setRelationshipIDs(data.Relationships, castedTarget)
+	if isResource {
+		return resource.setRelationships(data.Relationships, pointer)
+	}
+
+	return setRelationshipIDs(data.Relationships, castedTarget, pointer)
 }
 
 // extracts all found relationships and set's them via SetToOneReferenceID or
-// SetToManyReferenceIDs
-func setRelationshipIDs(relationships map[string]Relationship, target UnmarshalIdentifier) error {
+// SetToManyReferenceIDs, or their polymorphic counterparts if implemented
+func setRelationshipIDs(relationships map[string]Relationship, target UnmarshalIdentifier, pointer string) error {
 	for name, rel := range relationships {
-		// if Data is nil, it means that we have an empty toOne relationship
-		if rel.Data == nil {
-			castedToOne, ok := target.(UnmarshalToOneRelations)
-			if !ok {
-				return fmt.Errorf("struct %s does not implement UnmarshalToOneRelations", reflect.TypeOf(target))
+		relationshipPointer := pointer + "/relationships/" + name
+
+		// valid toMany case
+		if rel.Data != nil && rel.Data.DataArray != nil {
+			references := make([]ReferenceID, len(rel.Data.DataArray))
+			for index, relData := range rel.Data.DataArray {
+				references[index] = ReferenceID{ID: relData.ID, Type: relData.Type, Name: name, Relationship: ToManyRelationship}
 			}
 
-			err := castedToOne.SetToOneReferenceID(name, "")
-			if err != nil {
+			if err := setToManyReferences(target, name, references, relationshipPointer); err != nil {
 				return err
 			}
 			continue
 		}
 
-		// valid toOne case
-		if rel.Data.DataObject != nil {
-			castedToOne, ok := target.(UnmarshalToOneRelations)
-			if !ok {
-				return fmt.Errorf("struct %s does not implement UnmarshalToOneRelations", reflect.TypeOf(target))
-			}
-			err := castedToOne.SetToOneReferenceID(name, rel.Data.DataObject.ID)
-			if err != nil {
-				return err
-			}
+		// if Data or its DataObject is nil, it means that we have an empty toOne relationship
+		reference := ReferenceID{Name: name, Relationship: ToOneRelationship}
+		if rel.Data != nil && rel.Data.DataObject != nil {
+			reference.ID = rel.Data.DataObject.ID
+			reference.Type = rel.Data.DataObject.Type
 		}
 
-		// valid toMany case
-		if rel.Data.DataArray != nil {
-			castedToMany, ok := target.(UnmarshalToManyRelations)
-			if !ok {
-				return fmt.Errorf("struct %s does not implement UnmarshalToManyRelations", reflect.TypeOf(target))
-			}
-			IDs := make([]string, len(rel.Data.DataArray))
-			for index, relData := range rel.Data.DataArray {
-				IDs[index] = relData.ID
-			}
-			err := castedToMany.SetToManyReferenceIDs(name, IDs)
-			if err != nil {
-				return err
-			}
+		if err := setToOneReference(target, name, reference, relationshipPointer); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+func setToOneReference(target UnmarshalIdentifier, name string, reference ReferenceID, pointer string) error {
+	if polymorphic, ok := target.(UnmarshalPolymorphicToOneRelations); ok {
+		return polymorphic.SetToOneReference(name, reference)
+	}
+
+	castedToOne, ok := target.(UnmarshalToOneRelations)
+	if !ok {
+		return &UnmarshalError{
+			Err:     ErrInvalidRelationship,
+			Pointer: pointer + "/data",
+			Detail:  fmt.Sprintf("struct %s does not implement UnmarshalToOneRelations", reflect.TypeOf(target)),
+		}
+	}
+
+	return castedToOne.SetToOneReferenceID(name, reference.ID)
+}
+
+func setToManyReferences(target UnmarshalIdentifier, name string, references []ReferenceID, pointer string) error {
+	if polymorphic, ok := target.(UnmarshalPolymorphicToManyRelations); ok {
+		return polymorphic.SetToManyReferences(name, references)
+	}
+
+	castedToMany, ok := target.(UnmarshalToManyRelations)
+	if !ok {
+		return &UnmarshalError{
+			Err:     ErrInvalidRelationship,
+			Pointer: pointer + "/data",
+			Detail:  fmt.Sprintf("struct %s does not implement UnmarshalToManyRelations", reflect.TypeOf(target)),
+		}
+	}
+
+	IDs := make([]string, len(references))
+	for index, reference := range references {
+		IDs[index] = reference.ID
+	}
+
+	return castedToMany.SetToManyReferenceIDs(name, IDs)
+}
+
 func checkType(incomingType string, target UnmarshalIdentifier) error {
 	actualType := getStructType(target)
 	if incomingType != actualType {
@@ -3,6 +3,7 @@ package jsonapi
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -240,6 +241,52 @@ var _ = Describe("Unmarshal", func() {
 			Expect(err.Error()).To(Equal("Cannot unmarshal array to struct target jsonapi.SimplePost"))
 		})
 
+		Context("unmarshal errors", func() {
+			unmarshalError := func(err error) *UnmarshalError {
+				var result *UnmarshalError
+				Expect(errors.As(err, &result)).To(BeTrue())
+				return result
+			}
+
+			It("points to missing data", func() {
+				var post SimplePost
+				err := Unmarshal([]byte(`{"meta": {}}`), &post)
+				Expect(errors.Is(err, ErrMissingData)).To(BeTrue())
+				Expect(unmarshalError(err).Pointer).To(Equal("/data"))
+			})
+
+			It("points to a missing type", func() {
+				var posts []SimplePost
+				err := Unmarshal([]byte(`{"data": [{"type": "simplePosts"}, {"id": "2"}]}`), &posts)
+				Expect(errors.Is(err, ErrMissingData)).To(BeTrue())
+				Expect(unmarshalError(err).Pointer).To(Equal("/data/1/type"))
+			})
+
+			It("points to a mismatching type", func() {
+				var post SimplePost
+				err := Unmarshal([]byte(`{"data": {"type": "comments"}}`), &post)
+				Expect(errors.Is(err, ErrTypeMismatch)).To(BeTrue())
+				Expect(unmarshalError(err)).To(Equal(&UnmarshalError{
+					Err:     ErrTypeMismatch,
+					Pointer: "/data/type",
+					Detail:  "Type comments in JSON does not match target struct type simplePosts",
+				}))
+			})
+
+			It("points to an invalid relationship", func() {
+				post := NoRelationshipPosts{}
+				err := Unmarshal([]byte(`{"data": {"type": "posts", "relationships": {"author": {"data": null}}}}`), &post)
+				Expect(errors.Is(err, ErrInvalidRelationship)).To(BeTrue())
+				Expect(unmarshalError(err).Pointer).To(Equal("/data/relationships/author/data"))
+			})
+
+			It("marks malformed documents", func() {
+				var post SimplePost
+				err := Unmarshal([]byte(`{"data": 42}`), &post)
+				Expect(errors.Is(err, ErrInvalidDocument)).To(BeTrue())
+			})
+		})
+
 		Context("slice fields", func() {
 			It("unmarshal slice fields with single entry correctly", func() {
 				sliceJSON := []byte(`{
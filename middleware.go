@@ -0,0 +1,134 @@
+package api2go
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ResponseInfo describes a response that was written by an api2go route
+type ResponseInfo struct {
+	// Status is the status code of the response, 0 if nothing was written
+	Status int
+	// Duration is the time from receiving the request until the response was written
+	Duration time.Duration
+	// Err is the error returned by the resource or by api2go, nil on success
+	Err error
+}
+
+// ResponseHookFunc is called after an api2go route wrote its response
+type ResponseHookFunc func(APIContexter, *http.Request, ResponseInfo)
+
+// statusWriter records the status code written to a http.ResponseWriter
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap returns the original http.ResponseWriter for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush sends buffered data to the client if the original
+// http.ResponseWriter supports it
+func (w *statusWriter) Flush() {
+	flusher, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	flusher.Flush()
+}
+
+// Hijack takes over the connection if the original http.ResponseWriter
+// supports it
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking: %w", w.ResponseWriter, http.ErrNotSupported)
+	}
+
+	conn, buffer, err := hijacker.Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+
+	return conn, buffer, err
+}
+
+// written returns true if a response has been started
+func (w *statusWriter) written() bool {
+	return w.status != 0
+}
+
+// Action identifies one of the generated routes of a resource
+type Action string
+
+// The actions of the generated routes
+const (
+	ActionOptions                  Action = "options"
+	ActionFindAll                  Action = "findAll"
+	ActionFindOne                  Action = "findOne"
+	ActionCreate                   Action = "create"
+	ActionUpdate                   Action = "update"
+	ActionDelete                   Action = "delete"
+	ActionReadRelated              Action = "readRelated"
+	ActionReadRelationship         Action = "readRelationship"
+	ActionReplaceRelationship      Action = "replaceRelationship"
+	ActionAddToManyRelationship    Action = "addToManyRelationship"
+	ActionDeleteToManyRelationship Action = "deleteToManyRelationship"
+)
+
+// Resource is a registered resource, it is returned by AddResource
+type Resource struct {
+	res *resource
+}
+
+// UseMiddleware registers middlewares that run before all routes of the
+// resource, after the middlewares of the api.
+func (r *Resource) UseMiddleware(middleware ...HandlerFunc) {
+	r.UseMiddlewareFor("", middleware...)
+}
+
+// UseMiddlewareFor registers middlewares that only run before the routes of
+// the given action, after the middlewares of the resource.
+func (r *Resource) UseMiddlewareFor(action Action, middleware ...HandlerFunc) {
+	if r.res.middlewares == nil {
+		r.res.middlewares = map[Action][]HandlerFunc{}
+	}
+	r.res.middlewares[action] = append(r.res.middlewares[action], middleware...)
+}
+
+// middlewareChain runs the middlewares of the resource and the action
+func (res *resource) middlewareChain(action Action, c APIContexter, w *statusWriter, r *http.Request) bool {
+	for _, scope := range []Action{"", action} {
+		for _, middleware := range res.middlewares[scope] {
+			middleware(c, w, r)
+			if w.written() {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,181 @@
+package api2go
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (r *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	r.hijacked = true
+	return nil, nil, nil
+}
+
+var _ = Describe("Middleware chain", func() {
+	var (
+		api       *API
+		rec       *httptest.ResponseRecorder
+		source    *fixtureSource
+		calls     []string
+		responses []ResponseInfo
+	)
+
+	BeforeEach(func() {
+		calls = nil
+		responses = nil
+		source = &fixtureSource{map[string]*Post{}, false}
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		api.UseMiddleware(
+			func(c APIContexter, w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, "auth")
+				if r.Header.Get("Authorization") == "" {
+					w.WriteHeader(http.StatusUnauthorized)
+				}
+			},
+			func(c APIContexter, w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, "audit")
+			},
+		)
+		api.UseResponseHook(func(c APIContexter, r *http.Request, info ResponseInfo) {
+			responses = append(responses, info)
+		})
+		rec = httptest.NewRecorder()
+	})
+
+	It("skips the remaining middlewares and the route if a middleware wrote a response", func() {
+		req, err := http.NewRequest("POST", "/v1/posts", strings.NewReader(`{"data": {"type": "posts", "attributes": {"title": "New"}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+		Expect(calls).To(Equal([]string{"auth"}))
+		Expect(source.posts).To(BeEmpty())
+		Expect(responses).To(HaveLen(1))
+		Expect(responses[0].Status).To(Equal(http.StatusUnauthorized))
+		Expect(responses[0].Err).ToNot(HaveOccurred())
+	})
+
+	It("calls the route and passes the result to the response hooks", func() {
+		req, err := http.NewRequest("POST", "/v1/posts", strings.NewReader(`{"data": {"type": "posts", "attributes": {"title": "New"}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer secret")
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(calls).To(Equal([]string{"auth", "audit"}))
+		Expect(responses).To(HaveLen(1))
+		Expect(responses[0].Status).To(Equal(http.StatusCreated))
+		Expect(responses[0].Duration).To(BeNumerically(">", 0))
+	})
+
+	It("passes errors to the response hooks", func() {
+		req, err := http.NewRequest("GET", "/v1/posts/404", nil)
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Authorization", "Bearer secret")
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(responses).To(HaveLen(1))
+		Expect(responses[0].Status).To(Equal(http.StatusNotFound))
+		Expect(responses[0].Err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Resource middleware", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *fixtureSource
+		calls  []string
+	)
+
+	denyCreate := func(c APIContexter, w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "create")
+		w.WriteHeader(http.StatusForbidden)
+	}
+
+	BeforeEach(func() {
+		calls = nil
+		source = &fixtureSource{map[string]*Post{"1": {ID: "1", Title: "Hello, World!"}}, false}
+		api = NewAPI("v1")
+		api.UseMiddleware(func(c APIContexter, w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "api")
+		})
+		posts := api.AddResource(Post{}, source)
+		posts.UseMiddleware(func(c APIContexter, w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "posts")
+		})
+		posts.UseMiddlewareFor(ActionCreate, denyCreate)
+		api.AddResource(User{}, &userSource{false})
+		api.EnableAtomicOperations()
+		rec = httptest.NewRecorder()
+	})
+
+	It("runs the middlewares of the api and the resource in order", func() {
+		req, err := http.NewRequest("GET", "/v1/posts/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(calls).To(Equal([]string{"api", "posts"}))
+	})
+
+	It("runs action middlewares only for their routes", func() {
+		req, err := http.NewRequest("POST", "/v1/posts", strings.NewReader(`{"data": {"type": "posts", "attributes": {"title": "New"}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(calls).To(Equal([]string{"api", "posts", "create"}))
+		Expect(source.posts).To(HaveLen(1))
+	})
+
+	It("does not run the middlewares for other resources", func() {
+		req, err := http.NewRequest("OPTIONS", "/v1/users", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(calls).To(Equal([]string{"api"}))
+	})
+
+	It("runs the middlewares for atomic operations", func() {
+		req, err := http.NewRequest("POST", "/v1/operations", strings.NewReader(`{"atomic:operations": [
+			{"op": "add", "data": {"type": "posts", "attributes": {"title": "New"}}}
+		]}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusForbidden))
+		Expect(calls).To(Equal([]string{"api", "posts", "create"}))
+		Expect(source.posts).To(HaveLen(1))
+	})
+})
+
+var _ = Describe("Status writer", func() {
+	It("forwards Flush to the original writer", func() {
+		rec := httptest.NewRecorder()
+		w := &statusWriter{ResponseWriter: rec}
+		Expect(http.NewResponseController(w).Flush()).To(Succeed())
+		Expect(rec.Flushed).To(BeTrue())
+		Expect(w.written()).To(BeTrue())
+	})
+
+	It("forwards Hijack to the original writer if it supports it", func() {
+		w := &statusWriter{ResponseWriter: httptest.NewRecorder()}
+		_, _, err := w.Hijack()
+		Expect(err).To(MatchError(http.ErrNotSupported))
+		Expect(w.written()).To(BeFalse())
+
+		rec := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+		w = &statusWriter{ResponseWriter: rec}
+		_, _, err = w.Hijack()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(rec.hijacked).To(BeTrue())
+		Expect(w.written()).To(BeTrue())
+	})
+})
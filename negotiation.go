@@ -0,0 +1,290 @@
+package api2go
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+const (
+	codeUnsupportedMediaType = "API2GO_UNSUPPORTED_MEDIA_TYPE"
+	codeNotAcceptable        = "API2GO_NOT_ACCEPTABLE"
+)
+
+// negotiation contains the extensions and profiles that were negotiated for
+// one request and the extensions and profiles that are applied to the response.
+type negotiation struct {
+	extensions      []string
+	profiles        []string
+	applied         []string
+	appliedProfiles []string
+}
+
+type negotiationContextKey struct{}
+
+func getNegotiation(r *http.Request) negotiation {
+	if n, ok := r.Context().Value(negotiationContextKey{}).(negotiation); ok {
+		return n
+	}
+
+	return negotiation{}
+}
+
+func withNegotiation(r *http.Request, n negotiation) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), negotiationContextKey{}, n))
+}
+
+// withExtension marks an extension as applied to the response of r
+func withExtension(r *http.Request, extension string) *http.Request {
+	n := getNegotiation(r)
+	if containsString(n.applied, extension) {
+		return r
+	}
+
+	n.applied = append(append([]string{}, n.applied...), extension)
+	return withNegotiation(r, n)
+}
+
+// withRequestedExtension adds an extension the client asked for to r
+func withRequestedExtension(r *http.Request, extension string) *http.Request {
+	n := getNegotiation(r)
+	if containsString(n.extensions, extension) {
+		return r
+	}
+
+	n.extensions = append(append([]string{}, n.extensions...), extension)
+	return withNegotiation(r, n)
+}
+
+// withRequestedProfile adds a supported profile the client asked for to r
+func withRequestedProfile(r *http.Request, profile string) *http.Request {
+	n := getNegotiation(r)
+	if containsString(n.profiles, profile) {
+		return r
+	}
+
+	n.profiles = append(append([]string{}, n.profiles...), profile)
+	return withNegotiation(r, n)
+}
+
+// withAppliedProfiles marks the negotiated profiles that obj applied as
+// applied to the response of r
+func withAppliedProfiles(r *http.Request, obj Responder) *http.Request {
+	responder, ok := obj.(ProfilesResponder)
+	if !ok {
+		return r
+	}
+
+	n := getNegotiation(r)
+	applied := append([]string{}, n.appliedProfiles...)
+	for _, profile := range responder.AppliedProfiles() {
+		if containsString(n.profiles, profile) && !containsString(applied, profile) {
+			applied = append(applied, profile)
+		}
+	}
+	n.appliedProfiles = applied
+
+	return withNegotiation(r, n)
+}
+
+// SupportExtension registers the URI of a JSON:API extension the api
+// supports. Requests and Accept headers using other extensions are rejected.
+func (api *API) SupportExtension(uri string) {
+	if !containsString(api.extensions, uri) {
+		api.extensions = append(api.extensions, uri)
+	}
+}
+
+// SupportProfile registers the URI of a JSON:API profile the api applies if a
+// client asks for it. Unknown profiles are ignored as the spec requires.
+func (api *API) SupportProfile(uri string) {
+	if !containsString(api.profiles, uri) {
+		api.profiles = append(api.profiles, uri)
+	}
+}
+
+// contentType returns the Content-Type header for the response of r, which
+// advertises the applied extensions and profiles.
+func (api *API) contentType(r *http.Request) string {
+	n := getNegotiation(r)
+	contentType := api.ContentType
+	if len(n.applied) > 0 {
+		contentType += fmt.Sprintf(`;ext="%s"`, strings.Join(n.applied, " "))
+	}
+	if len(n.appliedProfiles) > 0 {
+		contentType += fmt.Sprintf(`;profile="%s"`, strings.Join(n.appliedProfiles, " "))
+	}
+
+	return contentType
+}
+
+// negotiate checks the Content-Type and Accept headers of r as described in
+// https://jsonapi.org/format/#content-negotiation and returns r with the
+// negotiated extensions and profiles.
+func (api *API) negotiate(r *http.Request) (*http.Request, error) {
+	mediaType := api.mediaType()
+
+	if header := r.Header.Get("Content-Type"); header != "" {
+		contentType, params, err := mime.ParseMediaType(header)
+		if err != nil {
+			return r, newNegotiationError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "Content-Type header is invalid", err.Error())
+		}
+
+		if contentType == mediaType {
+			if err := api.checkMediaTypeParams(params); err != nil {
+				return r, newNegotiationError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "Unsupported media type", err.Error())
+			}
+
+			r = withNegotiation(r, negotiation{
+				extensions: splitURIs(params["ext"]),
+				profiles:   api.supportedProfiles(splitURIs(params["profile"])),
+			})
+		}
+	}
+
+	header := r.Header.Get("Accept")
+	if header == "" {
+		return r, nil
+	}
+
+	acceptable := false
+	var reasons []string
+	for _, entry := range splitMediaTypes(header) {
+		accepted, params, err := mime.ParseMediaType(entry)
+		if err != nil {
+			continue
+		}
+
+		if q, ok := params["q"]; ok {
+			delete(params, "q")
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				continue
+			}
+		}
+
+		switch accepted {
+		case "*/*", strings.Split(mediaType, "/")[0] + "/*", "application/json":
+			// the responses are json, so clients that do not know the
+			// JSON:API media type can read them too
+			acceptable = true
+		case mediaType:
+			if err := api.checkMediaTypeParams(params); err != nil {
+				reasons = append(reasons, err.Error())
+				continue
+			}
+
+			for _, extension := range splitURIs(params["ext"]) {
+				r = withRequestedExtension(r, extension)
+			}
+			for _, profile := range api.supportedProfiles(splitURIs(params["profile"])) {
+				r = withRequestedProfile(r, profile)
+			}
+			return r, nil
+		}
+	}
+
+	if acceptable {
+		return r, nil
+	}
+
+	detail := fmt.Sprintf("Please accept %s", mediaType)
+	if len(reasons) > 0 {
+		detail = strings.Join(reasons, ", ")
+	}
+
+	return r, newNegotiationError(http.StatusNotAcceptable, codeNotAcceptable, "No acceptable media type in Accept header", detail)
+}
+
+// mediaType returns the configured content type without any parameters
+func (api *API) mediaType() string {
+	mediaType, _, err := mime.ParseMediaType(api.ContentType)
+	if err != nil {
+		return api.ContentType
+	}
+
+	return mediaType
+}
+
+func (api *API) checkMediaTypeParams(params map[string]string) error {
+	for name, value := range params {
+		switch name {
+		case "profile":
+		case "ext":
+			for _, extension := range splitURIs(value) {
+				if !containsString(api.extensions, extension) {
+					return fmt.Errorf("extension %s is not supported", extension)
+				}
+			}
+		default:
+			return fmt.Errorf("media type parameter %s is not supported", name)
+		}
+	}
+
+	return nil
+}
+
+func (api *API) supportedProfiles(profiles []string) []string {
+	var result []string
+	for _, profile := range profiles {
+		if containsString(api.profiles, profile) {
+			result = append(result, profile)
+		}
+	}
+
+	return result
+}
+
+// splitMediaTypes splits an Accept header into its media ranges, commas
+// inside of quoted parameter values are kept.
+func splitMediaTypes(header string) []string {
+	var (
+		result []string
+		quoted bool
+		start  int
+	)
+
+	for i, char := range header {
+		switch char {
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				result = append(result, strings.TrimSpace(header[start:i]))
+				start = i + 1
+			}
+		}
+	}
+
+	return append(result, strings.TrimSpace(header[start:]))
+}
+
+func splitURIs(value string) []string {
+	return strings.Fields(value)
+}
+
+func containsString(haystack []string, needle string) bool {
+	for _, value := range haystack {
+		if value == needle {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newNegotiationError(status int, code, title, detail string) HTTPError {
+	httpError := NewHTTPError(nil, title, status)
+	httpError.Errors = []jsonapi.Error{{
+		Status: strconv.Itoa(status),
+		Code:   code,
+		Title:  title,
+		Detail: detail,
+	}}
+
+	return httpError
+}
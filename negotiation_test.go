@@ -0,0 +1,122 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type profileSource struct {
+	applied []string
+}
+
+func (s profileSource) FindAll(req Request) (Responder, error) {
+	return &Response{Res: []Post{{ID: "1", Title: "Hello, World!"}}, Profiles: s.applied}, nil
+}
+
+var _ = Describe("Content negotiation", func() {
+	const profile = "https://example.com/profiles/timestamps"
+
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}, false})
+		api.SupportProfile(profile)
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(method, contentType, accept string) {
+		req, err := http.NewRequest(method, "/v1/posts", strings.NewReader(`{"data": {"type": "posts", "attributes": {"title": "New"}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("accepts requests without headers", func() {
+		doRequest("GET", "", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypHeader))
+	})
+
+	It("accepts wildcards and ignores entries with unsupported parameters", func() {
+		doRequest("GET", "", `application/vnd.api+json;charset=utf-8, */*;q=0.8`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+
+	It("responds with 415 for unsupported media type parameters", func() {
+		doRequest("POST", "application/vnd.api+json; charset=utf-8", "")
+		Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+		Expect(rec.Body.String()).To(ContainSubstring("media type parameter charset is not supported"))
+	})
+
+	It("responds with 415 for unsupported extensions", func() {
+		doRequest("POST", `application/vnd.api+json; ext="https://example.com/ext/unknown"`, "")
+		Expect(rec.Code).To(Equal(http.StatusUnsupportedMediaType))
+	})
+
+	It("responds with 406 if all json:api media types have unsupported parameters", func() {
+		doRequest("GET", "", `application/vnd.api+json; charset=utf-8`)
+		Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [{
+			"status": "406",
+			"code": "API2GO_NOT_ACCEPTABLE",
+			"title": "No acceptable media type in Accept header",
+			"detail": "media type parameter charset is not supported"
+		}]}`))
+	})
+
+	It("responds with 406 if no media type is acceptable", func() {
+		doRequest("GET", "", "text/html")
+		Expect(rec.Code).To(Equal(http.StatusNotAcceptable))
+	})
+
+	It("accepts clients that only accept json", func() {
+		doRequest("GET", "", "application/json")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypHeader))
+	})
+
+	It("advertises no profiles and extensions that are not applied", func() {
+		api.SupportExtension(AtomicExtension)
+		doRequest("GET", "", `application/vnd.api+json; ext="https://jsonapi.org/ext/atomic"; profile="`+profile+` https://example.com/unknown"`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(defaultContentTypHeader))
+	})
+
+	It("advertises the requested profiles the source applied", func() {
+		api = NewAPI("v1")
+		api.SupportProfile(profile)
+		api.SupportProfile("https://example.com/profiles/other")
+		api.AddResource(Post{}, profileSource{applied: []string{profile, "https://example.com/profiles/other"}})
+
+		doRequest("GET", "", `application/vnd.api+json; profile="`+profile+`"`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(`application/vnd.api+json;profile="` + profile + `"`))
+	})
+
+	It("advertises only the atomic extension on atomic operations", func() {
+		api.SupportExtension("https://example.com/ext/version")
+		api.EnableAtomicOperations()
+		req, err := http.NewRequest("POST", "/v1/operations", strings.NewReader(`{"atomic:operations": [{"op": "add", "data": {"type": "posts", "attributes": {"title": "New"}}}]}`))
+		Expect(err).ToNot(HaveOccurred())
+		req.Header.Set("Content-Type", `application/vnd.api+json; ext="https://example.com/ext/version https://jsonapi.org/ext/atomic"`)
+		req.Header.Set("Accept", `application/vnd.api+json; ext="https://example.com/ext/version https://jsonapi.org/ext/atomic"`)
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(`application/vnd.api+json;ext="https://jsonapi.org/ext/atomic"`))
+	})
+})
@@ -0,0 +1,732 @@
+package api2go
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification of generated documents
+const OpenAPIVersion = "3.1.0"
+
+// OpenAPIInfo contains the info object of a generated OpenAPI document
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// schema is a JSON object of an OpenAPI document
+type schema map[string]interface{}
+
+func schemaRef(name string) schema {
+	return schema{"$ref": "#/components/schemas/" + name}
+}
+
+// resourceSchemaName returns the name of the schema of a resource, which is
+// suffixed so it does not replace the shared schemas like links or meta
+func resourceSchemaName(name string) string {
+	return name + "Resource"
+}
+
+// OpenAPI returns an OpenAPI 3.1 document that describes all generated routes
+// of the registered resources.
+func (api *API) OpenAPI(info OpenAPIInfo) map[string]interface{} {
+	return api.openAPI(info, &api.info)
+}
+
+// ServeOpenAPI serves the OpenAPI document of the api as JSON under the given
+// path, e.g. "/v1/openapi.json". The document is generated on every request,
+// so resources can be added afterwards. The middlewares of the api run before.
+func (api *API) ServeOpenAPI(path string, info OpenAPIInfo) {
+	api.handlePlain("GET", path, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string, serverInfo information) error {
+		result, err := json.Marshal(api.openAPI(info, &serverInfo))
+		if err != nil {
+			return err
+		}
+
+		writeResult(w, result, http.StatusOK, "application/json")
+		return nil
+	})
+}
+
+func (api *API) openAPI(info OpenAPIInfo, serverInfo *information) map[string]interface{} {
+	document := map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info":    info,
+	}
+
+	if baseURL := strings.TrimRight(serverInfo.GetBaseURL(), "/"); baseURL != "" {
+		document["servers"] = []schema{{"url": baseURL}}
+	}
+
+	schemas := openAPIBaseSchemas()
+	paths := map[string]schema{}
+	tags := []schema{}
+
+	for _, res := range api.resources {
+		tags = append(tags, schema{"name": res.name})
+		schemas[resourceSchemaName(res.name)] = res.openAPISchema()
+		schemas[res.name+"Document"] = schema{
+			"type":     "object",
+			"required": []string{"data"},
+			"properties": schema{
+				"data":     schemaRef(resourceSchemaName(res.name)),
+				"included": schema{"type": "array", "items": schemaRef("resource")},
+				"links":    schemaRef("links"),
+				"meta":     schemaRef("meta"),
+			},
+		}
+		schemas[res.name+"Collection"] = schema{
+			"type":     "object",
+			"required": []string{"data"},
+			"properties": schema{
+				"data":     schema{"type": "array", "items": schemaRef(resourceSchemaName(res.name))},
+				"included": schema{"type": "array", "items": schemaRef("resource")},
+				"links":    schemaRef("links"),
+				"meta":     schemaRef("meta"),
+			},
+		}
+
+		res.openAPIPaths(paths)
+	}
+
+	if containsString(api.extensions, AtomicExtension) {
+		paths[api.routePrefix()+"/operations"] = schema{
+			"post": schema{
+				"operationId": "operations",
+				"summary":     "Performs atomic operations",
+				"requestBody": requestBody(schema{"type": "object", "required": []string{"atomic:operations"}}),
+				"responses": schema{
+					"200":     documentResponse("Results of the operations", schema{"type": "object"}),
+					"204":     schema{"description": "All operations succeeded without results"},
+					"400":     errorResponse(),
+					"403":     errorResponse(),
+					"404":     errorResponse(),
+					"406":     errorResponse(),
+					"409":     errorResponse(),
+					"415":     errorResponse(),
+					"422":     errorResponse(),
+					"default": errorResponse(),
+				},
+			},
+		}
+	}
+
+	document["tags"] = tags
+	document["paths"] = paths
+	document["components"] = schema{
+		"schemas": schemas,
+		"responses": schema{
+			"error": documentResponse("Error", schemaRef("errors")),
+		},
+	}
+
+	return document
+}
+
+// routePrefix returns the prefix of all generated routes
+func (api *API) routePrefix() string {
+	prefix := strings.Trim(api.info.prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+
+	return "/" + prefix
+}
+
+// openAPIPaths adds all generated routes of the resource to paths
+func (res *resource) openAPIPaths(paths map[string]schema) {
+	baseURL := res.api.routePrefix() + "/" + res.name
+	idParameter := schema{"name": "id", "in": "path", "required": true, "schema": schema{"type": "string"}}
+
+	collection := schema{}
+	if res.implementsFindAll() {
+		collection["get"] = openAPIOperation(res.name, "findAll", "Returns all "+res.name, res.collectionParameters(),
+			schema{"200": documentResponse("A list of "+res.name, schemaRef(res.name+"Collection"))}, http.StatusBadRequest)
+	}
+	_, isCreator := sourceAs[ResourceCreator](res.source)
+	_, isBulkCreator := sourceAs[BulkCreator](res.source)
+	if isCreator || isBulkCreator {
+		document, summary := schemaRef(res.name+"Document"), "Creates a resource of "+res.name
+		if isBulkCreator && isCreator {
+			document, summary = schema{"oneOf": []schema{document, schemaRef(res.name + "Collection")}}, "Creates one or multiple resources of "+res.name
+		} else if isBulkCreator {
+			document, summary = schemaRef(res.name+"Collection"), "Creates multiple resources of "+res.name
+		}
+		create := openAPIOperation(res.name, "create", summary, nil, schema{
+			"201": documentResponse("The created resource", document),
+			"202": schema{"description": "The creation is processed later"},
+			"204": schema{"description": "The resource was created as sent"},
+		}, http.StatusBadRequest, http.StatusForbidden, http.StatusConflict, http.StatusUnprocessableEntity)
+		create["requestBody"] = requestBody(document)
+		collection["post"] = create
+	}
+	if _, ok := sourceAs[BulkUpdater](res.source); ok {
+		update := openAPIOperation(res.name, "bulkUpdate", "Updates multiple resources of "+res.name, nil, schema{
+			"200": documentResponse("The updated resources", schemaRef(res.name+"Collection")),
+			"202": schema{"description": "The update is processed later"},
+			"204": schema{"description": "The resources were updated as sent"},
+		}, http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity)
+		update["requestBody"] = requestBody(schemaRef(res.name + "Collection"))
+		collection["patch"] = update
+	}
+	if _, ok := sourceAs[BulkDeleter](res.source); ok {
+		remove := openAPIOperation(res.name, "bulkDelete", "Deletes multiple resources of "+res.name, nil, schema{
+			"200": documentResponse("The meta of the deletion", metaDocument()),
+			"202": schema{"description": "The deletion is processed later"},
+			"204": schema{"description": "The resources were deleted"},
+		}, http.StatusBadRequest, http.StatusConflict)
+		remove["requestBody"] = requestBody(schema{
+			"type":       "object",
+			"required":   []string{"data"},
+			"properties": schema{"data": toManyLinkage()},
+		})
+		collection["delete"] = remove
+	}
+	if len(collection) > 0 {
+		paths[baseURL] = collection
+	}
+
+	single := schema{}
+	if _, ok := sourceAs[ResourceGetter](res.source); ok {
+		single["get"] = openAPIOperation(res.name, "findOne", "Returns one resource of "+res.name, res.fetchParameters(),
+			schema{"200": documentResponse("The resource", schemaRef(res.name+"Document"))}, http.StatusBadRequest)
+	}
+	if _, ok := sourceAs[ResourceUpdater](res.source); ok {
+		update := openAPIOperation(res.name, "update", "Updates a resource of "+res.name, nil, schema{
+			"200": documentResponse("The updated resource", schemaRef(res.name+"Document")),
+			"202": schema{"description": "The update is processed later"},
+			"204": schema{"description": "The resource was updated as sent"},
+		}, http.StatusBadRequest, http.StatusConflict, http.StatusPreconditionFailed, http.StatusUnprocessableEntity)
+		update["requestBody"] = requestBody(schemaRef(res.name + "Document"))
+		single["patch"] = update
+	}
+	if _, ok := sourceAs[ResourceDeleter](res.source); ok {
+		single["delete"] = openAPIOperation(res.name, "delete", "Deletes a resource of "+res.name, nil, schema{
+			"200": documentResponse("The meta of the deletion", metaDocument()),
+			"202": schema{"description": "The deletion is processed later"},
+			"204": schema{"description": "The resource was deleted"},
+		}, http.StatusPreconditionFailed)
+	}
+	if len(single) > 0 {
+		single["parameters"] = []schema{idParameter}
+		paths[baseURL+"/{id}"] = single
+	}
+
+	_, isGetter := sourceAs[ResourceGetter](res.source)
+	_, isUpdater := sourceAs[ResourceUpdater](res.source)
+	_, isRelationshipUpdater := relationshipUpdater(res.source)
+	updatesRelationships := isUpdater || isRelationshipUpdater
+	for _, relation := range res.references() {
+		linkage := toOneLinkage()
+		if isToManyReference(relation) {
+			linkage = toManyLinkage()
+		}
+		linkageDocument := schema{
+			"type":       "object",
+			"required":   []string{"data"},
+			"properties": schema{"data": linkage, "links": schemaRef("links"), "meta": schemaRef("meta")},
+		}
+		operationID := relation.Name + "Relationship"
+
+		relationship := schema{}
+		if isGetter {
+			relationship["get"] = openAPIOperation(res.name, operationID, "Returns the "+relation.Name+" relationship", nil,
+				schema{"200": documentResponse("The resource linkage", linkageDocument)})
+		}
+		if updatesRelationships {
+			replace := openAPIOperation(res.name, "replace"+upperFirst(operationID), "Replaces the "+relation.Name+" relationship", nil, schema{
+				"200": documentResponse("The updated resource linkage", linkageDocument),
+				"202": schema{"description": "The update is processed later"},
+				"204": schema{"description": "The relationship was replaced"},
+			}, http.StatusBadRequest, http.StatusConflict, http.StatusPreconditionFailed)
+			replace["requestBody"] = requestBody(linkageDocument)
+			relationship["patch"] = replace
+		}
+
+		if updatesRelationships && res.editsToManyRelation(relation) {
+			for method, action := range map[string]string{"post": "add", "delete": "delete"} {
+				edit := openAPIOperation(res.name, action+upperFirst(operationID), upperFirst(action)+"s members of the "+relation.Name+" relationship", nil, schema{
+					"200": documentResponse("The updated resource linkage", linkageDocument),
+					"202": schema{"description": "The update is processed later"},
+					"204": schema{"description": "The relationship was updated"},
+				}, http.StatusBadRequest, http.StatusConflict, http.StatusPreconditionFailed)
+				edit["requestBody"] = requestBody(linkageDocument)
+				relationship[method] = edit
+			}
+		}
+		if len(relationship) > 0 {
+			relationship["parameters"] = []schema{idParameter}
+			paths[baseURL+"/{id}/relationships/"+relation.Name] = relationship
+		}
+
+		if relation.IsPolymorphic() {
+			if !isGetter {
+				continue
+			}
+			if parameters, document, ok := res.polymorphicRelated(relation); ok {
+				paths[baseURL+"/{id}/"+relation.Name] = schema{
+					"parameters": []schema{idParameter},
+					"get": openAPIOperation(res.name, relation.Name, "Returns the related "+relation.Name, parameters,
+						schema{"200": documentResponse("The related "+strings.Join(relation.Types, " or "), document)}, http.StatusBadRequest),
+				}
+			}
+		} else if related := res.api.findResource(relation.Type); related != nil && related.findsRelated(relation) {
+			parameters, document := related.fetchParameters(), schemaRef(related.name+"Document")
+			if isToManyReference(relation) {
+				parameters, document = related.collectionParameters(), schemaRef(related.name+"Collection")
+			}
+			paths[baseURL+"/{id}/"+relation.Name] = schema{
+				"parameters": []schema{idParameter},
+				"get": openAPIOperation(res.name, relation.Name, "Returns the related "+relation.Name, parameters,
+					schema{"200": documentResponse("The related "+relation.Type, document)}, http.StatusBadRequest),
+			}
+		}
+	}
+}
+
+// polymorphicRelated returns the query parameters and the document schema of
+// the related route of a polymorphic relationship, ok is false if none of its
+// types is registered
+func (res *resource) polymorphicRelated(relation jsonapi.Reference) ([]schema, schema, bool) {
+	parameters := []schema{queryParameter("include", "Comma separated relationship paths to include")}
+	types := []schema{}
+	for _, related := range res.api.referencedResources(relation) {
+		parameters = append(parameters, queryParameter("fields["+related.name+"]", "Comma separated fields to return"))
+		types = append(types, schemaRef(resourceSchemaName(related.name)))
+	}
+	if len(types) == 0 {
+		return nil, nil, false
+	}
+
+	data := schema{"oneOf": append([]schema{{"type": "null"}}, types...)}
+	if isToManyReference(relation) {
+		data = schema{"type": "array", "items": schema{"oneOf": types}}
+	}
+
+	return parameters, schema{
+		"type":     "object",
+		"required": []string{"data"},
+		"properties": schema{
+			"data":     data,
+			"included": schema{"type": "array", "items": schemaRef("resource")},
+			"links":    schemaRef("links"),
+			"meta":     schemaRef("meta"),
+		},
+	}, true
+}
+
+// findsRelated returns true if the resources of relation can be loaded from
+// res without pagination
+func (res *resource) findsRelated(relation jsonapi.Reference) bool {
+	if _, ok := sourceAs[RelatedResourceFinder](res.source); ok {
+		return true
+	}
+	if isToManyReference(relation) {
+		return res.implementsFindAll()
+	}
+	_, ok := sourceAs[FindAll](res.source)
+	return ok
+}
+
+func (res *resource) implementsFindAll() bool {
+	if _, ok := sourceAs[FindAll](res.source); ok {
+		return true
+	}
+	if _, ok := sourceAs[PaginatedFindAll](res.source); ok {
+		return true
+	}
+	_, ok := sourceAs[CursorPaginatedFindAll](res.source)
+	return ok
+}
+
+// ptrPrototype returns a pointer to a new instance of the resource
+func (res *resource) ptrPrototype() interface{} {
+	if res.resourceType.Kind() == reflect.Struct {
+		return reflect.New(res.resourceType).Interface()
+	}
+
+	return res.prototype
+}
+
+// fetchParameters returns the query parameters of all GET routes
+func (res *resource) fetchParameters() []schema {
+	return []schema{
+		queryParameter("include", "Comma separated relationship paths to include"),
+		queryParameter("fields["+res.name+"]", "Comma separated fields to return"),
+	}
+}
+
+// collectionParameters returns the query parameters of routes returning
+// multiple resources
+func (res *resource) collectionParameters() []schema {
+	parameters := res.fetchParameters()
+
+	if sortable, ok := sourceAs[SortableResource](res.source); ok {
+		parameters = append(parameters, queryParameter("sort", "Comma separated fields to sort by, prefix with - for descending order. Possible fields: "+strings.Join(sortable.SortableFields(), ", ")))
+	}
+
+	if filterable, ok := sourceAs[FilterableResource](res.source); ok {
+		fields := []string{}
+		for field := range filterable.FilterableFields() {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		for _, field := range fields {
+			parameters = append(parameters, queryParameter("filter["+field+"]", "Value to filter by"))
+			for _, operator := range filterable.FilterableFields()[field] {
+				if operator == DefaultFilterOperator {
+					continue
+				}
+
+				description := "Value to filter by"
+				if containsString(listFilterOperators, operator) {
+					description = "Comma separated values to filter by"
+				}
+				parameters = append(parameters, queryParameter("filter["+field+"]["+operator+"]", description))
+			}
+		}
+	}
+
+	var pages []string
+	if _, ok := sourceAs[PaginatedFindAll](res.source); ok {
+		pages = append(pages, "number", "size", "offset", "limit")
+	}
+	if _, ok := sourceAs[CursorPaginatedFindAll](res.source); ok {
+		pages = append(pages, "after", "before")
+		if !containsString(pages, "size") {
+			pages = append(pages, "size")
+		}
+	}
+	for _, page := range pages {
+		parameters = append(parameters, queryParameter("page["+page+"]", "Pagination parameter"))
+	}
+
+	return parameters
+}
+
+// openAPISchema returns the schema of the resource object
+func (res *resource) openAPISchema() schema {
+	properties := schema{
+		"type":       schema{"type": "string", "const": res.name},
+		"id":         schema{"type": "string"},
+		"attributes": res.attributesSchema(),
+		"links":      schemaRef("links"),
+		"meta":       schemaRef("meta"),
+	}
+
+	if references := res.references(); len(references) > 0 {
+		relationships := schema{}
+		for _, reference := range references {
+			linkage := toOneLinkage()
+			if isToManyReference(reference) {
+				linkage = toManyLinkage()
+			}
+			relationships[reference.Name] = schema{
+				"type":       "object",
+				"properties": schema{"data": linkage, "links": schemaRef("links"), "meta": schemaRef("meta")},
+			}
+		}
+		properties["relationships"] = schema{"type": "object", "properties": relationships}
+	}
+
+	return schema{
+		"type":       "object",
+		"required":   []string{"type"},
+		"properties": properties,
+	}
+}
+
+func isToManyReference(reference jsonapi.Reference) bool {
+	if reference.Relationship == jsonapi.DefaultRelationship {
+		return jsonapi.Pluralize(reference.Name) == reference.Name
+	}
+
+	return reference.Relationship == jsonapi.ToManyRelationship
+}
+
+func toOneLinkage() schema {
+	return schema{"oneOf": []schema{schemaRef("resourceIdentifier"), {"type": "null"}}}
+}
+
+func toManyLinkage() schema {
+	return schema{"type": "array", "items": schemaRef("resourceIdentifier")}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// attributesSchema returns the schema of the attributes of the resource
+func (res *resource) attributesSchema() schema {
+	definition := res.resourceSchema()
+	if definition == nil {
+		return attributesSchema(res.resourceType)
+	}
+
+	properties := schema{}
+	required := []string{}
+	for _, attribute := range definition.Attributes {
+		property := schema{}
+		if attribute.Type != jsonapi.AnyAttribute {
+			property["type"] = string(attribute.Type)
+		}
+		properties[attribute.Name] = property
+
+		if attribute.Required {
+			required = append(required, attribute.Name)
+		}
+	}
+
+	result := schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		result["required"] = required
+	}
+
+	return result
+}
+
+// resourceSchema returns the schema of resources registered with a
+// jsonapi.Resource prototype
+func (res *resource) resourceSchema() *jsonapi.Schema {
+	switch prototype := res.prototype.(type) {
+	case *jsonapi.Resource:
+		return prototype.Schema
+	case jsonapi.Resource:
+		return prototype.Schema
+	}
+
+	return nil
+}
+
+// attributesSchema returns the schema of the attributes of a resource type
+func attributesSchema(resourceType reflect.Type) schema {
+	if resourceType.Kind() == reflect.Ptr {
+		resourceType = resourceType.Elem()
+	}
+
+	result := typeSchema(resourceType, map[reflect.Type]bool{})
+	if result["type"] != "object" {
+		return schema{"type": "object"}
+	}
+
+	return result
+}
+
+// typeSchema returns the JSON schema of the encoding/json representation of t
+func typeSchema(t reflect.Type, visited map[reflect.Type]bool) schema {
+	switch {
+	case t == timeType:
+		return schema{"type": "string", "format": "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return schema{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Ptr:
+		elem := typeSchema(t.Elem(), visited)
+		if elemType, ok := elem["type"].(string); ok {
+			elem["type"] = []string{elemType, "null"}
+		}
+		return elem
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return schema{"type": "array", "items": typeSchema(t.Elem(), visited)}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": typeSchema(t.Elem(), visited)}
+	case reflect.Struct:
+		if visited[t] {
+			return schema{"type": "object"}
+		}
+		visited[t] = true
+		defer delete(visited, t)
+
+		properties := schema{}
+		addStructProperties(t, properties, visited)
+		return schema{"type": "object", "properties": properties}
+	}
+
+	return schema{}
+}
+
+// addStructProperties adds the fields of t to properties, the fields of
+// embedded structs are added as if they were fields of t.
+func addStructProperties(t reflect.Type, properties schema, visited map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name := strings.Split(tag, ",")[0]
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			addStructProperties(fieldType, properties, visited)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = typeSchema(field.Type, visited)
+	}
+}
+
+// openAPIOperation returns an operation with responses, the error responses
+// of statuses and of the content negotiation
+func openAPIOperation(tag, name, summary string, parameters []schema, responses schema, statuses ...int) schema {
+	for _, status := range append(statuses, http.StatusNotAcceptable, http.StatusUnsupportedMediaType) {
+		responses[strconv.Itoa(status)] = errorResponse()
+	}
+	responses["default"] = errorResponse()
+	result := schema{
+		"operationId": tag + "." + name,
+		"summary":     summary,
+		"tags":        []string{tag},
+		"responses":   responses,
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	return result
+}
+
+func errorResponse() schema {
+	return schema{"$ref": "#/components/responses/error"}
+}
+
+// metaDocument returns the schema of documents that only contain meta
+func metaDocument() schema {
+	return schema{
+		"type":       "object",
+		"required":   []string{"meta"},
+		"properties": schema{"meta": schemaRef("meta")},
+	}
+}
+
+func queryParameter(name, description string) schema {
+	return schema{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"schema":      schema{"type": "string"},
+	}
+}
+
+func requestBody(content schema) schema {
+	return schema{
+		"required": true,
+		"content":  schema{defaultContentTypHeader: schema{"schema": content}},
+	}
+}
+
+func documentResponse(description string, content schema) schema {
+	return schema{
+		"description": description,
+		"content":     schema{defaultContentTypHeader: schema{"schema": content}},
+	}
+}
+
+func upperFirst(value string) string {
+	if value == "" {
+		return value
+	}
+
+	return strings.ToUpper(value[:1]) + value[1:]
+}
+
+// openAPIBaseSchemas returns the schemas shared by all resources
+func openAPIBaseSchemas() map[string]schema {
+	return map[string]schema{
+		"links": schema{
+			"type": "object",
+			"additionalProperties": schema{"oneOf": []schema{
+				{"type": "string"},
+				{"type": "object", "properties": schema{"href": schema{"type": "string"}, "meta": schemaRef("meta")}},
+				{"type": "null"},
+			}},
+		},
+		"meta": schema{"type": "object"},
+		"resourceIdentifier": schema{
+			"type":     "object",
+			"required": []string{"type", "id"},
+			"properties": schema{
+				"type": schema{"type": "string"},
+				"id":   schema{"type": "string"},
+			},
+		},
+		"resource": schema{
+			"type":     "object",
+			"required": []string{"type", "id"},
+			"properties": schema{
+				"type":          schema{"type": "string"},
+				"id":            schema{"type": "string"},
+				"attributes":    schema{"type": "object"},
+				"relationships": schema{"type": "object"},
+				"links":         schemaRef("links"),
+				"meta":          schemaRef("meta"),
+			},
+		},
+		"errors": schema{
+			"type":     "object",
+			"required": []string{"errors"},
+			"properties": schema{
+				"errors": schema{
+					"type": "array",
+					"items": schema{
+						"type": "object",
+						"properties": schema{
+							"id":     schema{"type": "string"},
+							"status": schema{"type": "string"},
+							"code":   schema{"type": "string"},
+							"title":  schema{"type": "string"},
+							"detail": schema{"type": "string"},
+							"source": schema{
+								"type": "object",
+								"properties": schema{
+									"pointer":   schema{"type": "string"},
+									"parameter": schema{"type": "string"},
+								},
+							},
+							"meta": schemaRef("meta"),
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,149 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type LinkEntry struct {
+	ID  string `json:"-"`
+	URL string `json:"url"`
+}
+
+func (l LinkEntry) GetID() string {
+	return l.ID
+}
+
+func (l LinkEntry) GetName() string {
+	return "links"
+}
+
+var _ = Describe("OpenAPI", func() {
+	var (
+		api      *API
+		rec      *httptest.ResponseRecorder
+		document map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		api = NewAPIWithBaseURL("v1", "http://localhost:31415")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{}, false})
+		api.AddResource(User{}, &userSource{false})
+		api.AddResource(Comment{}, &commentSource{false})
+		api.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "Blog", Version: "1.0.0"})
+		rec = httptest.NewRecorder()
+
+		req, err := http.NewRequest("GET", "/openapi.json", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal("application/json"))
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+	})
+
+	lookup := func(path ...string) interface{} {
+		var current interface{} = document
+		for _, key := range path {
+			Expect(current).To(HaveKey(key))
+			current = current.(map[string]interface{})[key]
+		}
+		return current
+	}
+
+	It("contains the info and servers", func() {
+		Expect(document["openapi"]).To(Equal("3.1.0"))
+		Expect(document["info"]).To(Equal(map[string]interface{}{"title": "Blog", "version": "1.0.0"}))
+		Expect(document["servers"]).To(Equal([]interface{}{map[string]interface{}{"url": "http://localhost:31415"}}))
+	})
+
+	It("describes all generated routes", func() {
+		Expect(lookup("paths", "/v1/posts")).To(HaveKey("get"))
+		Expect(lookup("paths", "/v1/posts")).To(HaveKey("post"))
+		Expect(lookup("paths", "/v1/posts/{id}")).To(SatisfyAll(HaveKey("get"), HaveKey("patch"), HaveKey("delete")))
+		Expect(lookup("paths", "/v1/posts/{id}/relationships/comments")).To(SatisfyAll(HaveKey("get"), HaveKey("patch"), HaveKey("post"), HaveKey("delete")))
+		Expect(lookup("paths", "/v1/posts/{id}/relationships/author")).ToNot(HaveKey("post"))
+		Expect(lookup("paths", "/v1/posts/{id}/comments", "get", "operationId")).To(Equal("posts.comments"))
+		Expect(lookup("paths")).ToNot(HaveKey(HavePrefix("/v1/users/{id}/")))
+	})
+
+	It("lists the status codes of the operations", func() {
+		Expect(lookup("paths", "/v1/posts/{id}", "delete", "responses")).To(SatisfyAll(
+			HaveKey("200"), HaveKey("202"), HaveKey("204"), HaveKey("406"), HaveKey("412"), HaveKey("415"), HaveKey("default")))
+		Expect(lookup("paths", "/v1/posts/{id}", "patch", "responses")).To(SatisfyAll(
+			HaveKey("400"), HaveKey("409"), HaveKey("412"), HaveKey("422")))
+		Expect(lookup("paths", "/v1/posts", "post", "responses")).To(SatisfyAll(
+			HaveKey("201"), HaveKey("400"), HaveKey("403"), HaveKey("409"), HaveKey("422")))
+		Expect(lookup("paths", "/v1/posts/{id}/relationships/comments", "patch", "responses")).To(SatisfyAll(
+			HaveKey("200"), HaveKey("400"), HaveKey("409"), HaveKey("412")))
+	})
+
+	It("only describes relationship routes the source supports", func() {
+		api = NewAPIWithBaseURL("v1", "http://localhost:31415")
+		api.AddResource(Post{}, &deleteOnlySource{})
+		paths := api.OpenAPI(OpenAPIInfo{Title: "Blog"})["paths"].(map[string]schema)
+
+		Expect(paths).To(HaveKey("/v1/posts/{id}"))
+		Expect(paths["/v1/posts/{id}"]).To(SatisfyAll(HaveKey("delete"), Not(HaveKey("get")), Not(HaveKey("patch"))))
+		Expect(paths).ToNot(HaveKey(HavePrefix("/v1/posts/{id}/")))
+	})
+
+	It("adds pagination parameters for paginated sources", func() {
+		names := []string{}
+		for _, parameter := range lookup("paths", "/v1/posts", "get", "parameters").([]interface{}) {
+			names = append(names, parameter.(map[string]interface{})["name"].(string))
+		}
+		Expect(names).To(ContainElements("include", "fields[posts]", "page[number]", "page[size]", "page[offset]", "page[limit]"))
+	})
+
+	It("derives attribute and relationship schemas", func() {
+		Expect(lookup("components", "schemas", "postsResource", "properties", "attributes")).To(Equal(map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"title": map[string]interface{}{"type": "string"},
+				"value": map[string]interface{}{},
+			},
+		}))
+		Expect(lookup("components", "schemas", "postsResource", "properties", "relationships", "properties", "comments", "properties", "data")).To(Equal(map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"$ref": "#/components/schemas/resourceIdentifier"},
+		}))
+		Expect(lookup("components", "schemas", "postsResource", "properties", "relationships", "properties", "author", "properties", "data")).To(HaveKey("oneOf"))
+	})
+
+	It("does not replace the shared schemas with resources of the same name", func() {
+		api.AddResource(LinkEntry{}, &struct{}{})
+		schemas := api.OpenAPI(OpenAPIInfo{Title: "Blog"})["components"].(schema)["schemas"].(map[string]schema)
+		Expect(schemas["links"]).To(HaveKeyWithValue("type", "object"))
+		Expect(schemas["links"]).To(HaveKey("additionalProperties"))
+		Expect(schemas["linksResource"]).To(HaveKey("properties"))
+		Expect(schemas["linksDocument"]["properties"]).To(HaveKeyWithValue("data", schemaRef("linksResource")))
+	})
+
+	It("runs the middlewares of the api", func() {
+		api.UseMiddleware(func(c APIContexter, w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		})
+
+		req, err := http.NewRequest("GET", "/openapi.json", nil)
+		Expect(err).ToNot(HaveOccurred())
+		rec = httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+
+		req.Header.Set("Authorization", "Bearer secret")
+		req.Header.Set("Accept", "application/json")
+		rec = httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+
+	It("returns the document without serving it", func() {
+		Expect(api.OpenAPI(OpenAPIInfo{Title: "Blog"})).To(HaveKey("paths"))
+	})
+})
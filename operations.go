@@ -0,0 +1,696 @@
+package api2go
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+const (
+	// AtomicExtension is the URI of the JSON:API atomic operations extension
+	AtomicExtension = "https://jsonapi.org/ext/atomic"
+
+	codeInvalidOperation = "API2GO_INVALID_ATOMIC_OPERATION"
+)
+
+// errResponseWritten stops processing of operations after a middleware of a
+// resource wrote a response
+var errResponseWritten = errors.New("response was written by a middleware")
+
+// The TransactionalResource interface can be optionally implemented by resources
+// that take part in atomic operations. Begin is called before the first
+// operation that targets the resource. If all operations succeed, Commit is called,
+// otherwise Rollback. All calls share the same Request.Context, which can be used
+// to store the transaction for the Create, Update and Delete calls. Resources
+// that do not implement it can only be changed by single operations.
+type TransactionalResource interface {
+	Begin(req Request) error
+	Commit(req Request) error
+	Rollback(req Request) error
+}
+
+type operationsDocument struct {
+	Operations []operation `json:"atomic:operations"`
+}
+
+type operation struct {
+	Op   string          `json:"op"`
+	Ref  *operationRef   `json:"ref,omitempty"`
+	Href string          `json:"href,omitempty"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+type operationRef struct {
+	Type         string `json:"type"`
+	ID           string `json:"id,omitempty"`
+	Lid          string `json:"lid,omitempty"`
+	Relationship string `json:"relationship,omitempty"`
+}
+
+type operationResult struct {
+	Data *jsonapi.Data          `json:"data,omitempty"`
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+// operationsRun holds the state of one atomic operations request
+type operationsRun struct {
+	api   *API
+	c     APIContexter
+	w     *statusWriter
+	r     *http.Request
+	info  information
+	lids  map[string]string
+	begun []*resource
+	// single is true if the request contains only one operation, which may
+	// target resources that do not implement TransactionalResource
+	single bool
+}
+
+// EnableAtomicOperations registers a POST route `/operations` that implements
+// the JSON:API atomic operations extension (https://jsonapi.org/ext/atomic).
+// All operations are dispatched to the ResourceCreator, ResourceUpdater and
+// ResourceDeleter implementations of the registered resources.
+func (api *API) EnableAtomicOperations() {
+	prefix := strings.Trim(api.info.prefix, "/")
+	route := "/operations"
+	if prefix != "" {
+		route = "/" + prefix + route
+	}
+
+	api.SupportExtension(AtomicExtension)
+	api.handle("POST", route, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string, info information) error {
+		return api.handleOperations(c, w, r, info)
+	})
+}
+
+func (api *API) handleOperations(c APIContexter, w http.ResponseWriter, r *http.Request, info information) error {
+	body, err := unmarshalRequest(r)
+	if err != nil {
+		return err
+	}
+
+	document := operationsDocument{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return NewHTTPError(err, err.Error(), http.StatusBadRequest)
+	}
+
+	if document.Operations == nil {
+		return newOperationError(http.StatusBadRequest, "Invalid document. Need an \"atomic:operations\" array", "/atomic:operations")
+	}
+
+	sw, ok := w.(*statusWriter)
+	if !ok {
+		sw = &statusWriter{ResponseWriter: w}
+	}
+	run := &operationsRun{api: api, c: c, w: sw, r: r, info: info, lids: map[string]string{}, single: len(document.Operations) == 1}
+
+	results := make([]operationResult, len(document.Operations))
+	hasData := false
+	for i, op := range document.Operations {
+		result, err := run.process(op)
+		if err != nil {
+			rollbackErr := run.rollback()
+			if err == errResponseWritten {
+				if rollbackErr != nil {
+					api.reportError(r, ErrorInfo{Err: rollbackErr})
+				}
+				return nil
+			}
+			if rollbackErr != nil {
+				return newRollbackError(errors.Join(err, rollbackErr))
+			}
+			return pointOperationError(api.httpError(err), i)
+		}
+
+		if result.Data != nil || len(result.Meta) > 0 {
+			hasData = true
+		}
+		results[i] = result
+	}
+
+	if err := run.commit(); err != nil {
+		return err
+	}
+
+	if !hasData {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"atomic:results": results})
+	if err != nil {
+		return err
+	}
+
+	writeResult(w, data, http.StatusOK, api.contentType(withExtension(r, AtomicExtension)))
+	return nil
+}
+
+func (o *operationsRun) process(op operation) (operationResult, error) {
+	if op.Href != "" {
+		return operationResult{}, newOperationError(http.StatusBadRequest, "Operations with \"href\" are not supported, use \"ref\" instead", "/href")
+	}
+
+	switch op.Op {
+	case "add":
+		if op.Ref != nil && op.Ref.Relationship != "" {
+			return o.processRelationship(op)
+		}
+		return o.processAdd(op)
+	case "update":
+		if op.Ref != nil && op.Ref.Relationship != "" {
+			return o.processRelationship(op)
+		}
+		return o.processUpdate(op)
+	case "remove":
+		if op.Ref == nil {
+			return operationResult{}, newOperationError(http.StatusBadRequest, "Remove operations need a \"ref\" object", "/ref")
+		}
+		if op.Ref.Relationship != "" {
+			return o.processRelationship(op)
+		}
+		return o.processRemove(op)
+	default:
+		return operationResult{}, newOperationError(http.StatusBadRequest, fmt.Sprintf("Unknown operation \"%s\"", op.Op), "/op")
+	}
+}
+
+func (o *operationsRun) processAdd(op operation) (operationResult, error) {
+	data, err := o.resolveData(op.Data)
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	res, err := o.resourceFor(data.Type, "/data/type", ActionCreate)
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	source, ok := sourceAs[ResourceCreator](res.source)
+	if !ok {
+		return operationResult{}, newOperationError(http.StatusForbidden, fmt.Sprintf("Resource %s does not support creation", res.name), "/data/type")
+	}
+
+	if err := res.checkClientID(data.ID); err != nil {
+		return operationResult{}, err
+	}
+
+	if err := o.begin(res); err != nil {
+		return operationResult{}, err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"data": data.raw})
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	newObj, err := res.unmarshalNew(body)
+	if err != nil {
+		return operationResult{}, unmarshalError(err)
+	}
+
+	if err := validate(newObj); err != nil {
+		return operationResult{}, err
+	}
+
+	response, err := source.Create(newObj, buildRequest(o.c, o.r))
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	created, ok := response.Result().(jsonapi.MarshalIdentifier)
+	if !ok {
+		if data.Lid != "" || response.StatusCode() == http.StatusCreated {
+			return operationResult{}, fmt.Errorf("Expected one newly created object by resource %s", res.name)
+		}
+		return operationResult{}, nil
+	}
+
+	if data.Lid != "" {
+		o.lids[lidKey(data.Type, data.Lid)] = created.GetID()
+	}
+
+	switch response.StatusCode() {
+	case http.StatusCreated:
+		return o.result(response)
+	case http.StatusAccepted, http.StatusNoContent:
+		return operationResult{}, nil
+	default:
+		return operationResult{}, fmt.Errorf("invalid status code %d from resource %s for method Create", response.StatusCode(), res.name)
+	}
+}
+
+func (o *operationsRun) processUpdate(op operation) (operationResult, error) {
+	data, err := o.resolveData(op.Data)
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	if op.Ref != nil {
+		id, err := o.resolveRef(op.Ref)
+		if err != nil {
+			return operationResult{}, err
+		}
+
+		if op.Ref.Type != data.Type || id != data.ID {
+			return operationResult{}, newOperationError(http.StatusConflict, "\"ref\" and \"data\" must identify the same resource", "/data")
+		}
+	}
+
+	res, err := o.resourceFor(data.Type, "/data/type", ActionUpdate)
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	source, ok := sourceAs[ResourceUpdater](res.source)
+	if !ok {
+		return operationResult{}, newOperationError(http.StatusForbidden, fmt.Sprintf("Resource %s does not support updates", res.name), "/data/type")
+	}
+
+	if err := o.begin(res); err != nil {
+		return operationResult{}, err
+	}
+
+	obj, err := source.FindOne(data.ID, buildRequest(o.c, o.r))
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"data": data.raw})
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	updatingObj, err := unmarshalExisting(obj.Result(), body)
+	if err != nil {
+		return operationResult{}, unmarshalError(err)
+	}
+
+	if err := validate(updatingObj); err != nil {
+		return operationResult{}, err
+	}
+
+	response, err := source.Update(updatingObj, buildRequest(o.c, o.r))
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	switch response.StatusCode() {
+	case http.StatusOK:
+		if response.Result() == nil {
+			response, err = source.FindOne(data.ID, buildRequest(o.c, o.r))
+			if err != nil {
+				return operationResult{}, err
+			}
+		}
+		return o.result(response)
+	case http.StatusAccepted, http.StatusNoContent:
+		return operationResult{}, nil
+	default:
+		return operationResult{}, fmt.Errorf("invalid status code %d from resource %s for method Update", response.StatusCode(), res.name)
+	}
+}
+
+func (o *operationsRun) processRemove(op operation) (operationResult, error) {
+	id, err := o.resolveRef(op.Ref)
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	res, err := o.resourceFor(op.Ref.Type, "/ref/type", ActionDelete)
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	source, ok := sourceAs[ResourceDeleter](res.source)
+	if !ok {
+		return operationResult{}, newOperationError(http.StatusForbidden, fmt.Sprintf("Resource %s does not support deletion", res.name), "/ref/type")
+	}
+
+	if err := o.begin(res); err != nil {
+		return operationResult{}, err
+	}
+
+	response, err := source.Delete(id, buildRequest(o.c, o.r))
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	switch response.StatusCode() {
+	case http.StatusOK:
+		return operationResult{Meta: response.Metadata()}, nil
+	case http.StatusAccepted, http.StatusNoContent:
+		return operationResult{}, nil
+	default:
+		return operationResult{}, fmt.Errorf("invalid status code %d from resource %s for method Delete", response.StatusCode(), res.name)
+	}
+}
+
+func (o *operationsRun) processRelationship(op operation) (operationResult, error) {
+	id, err := o.resolveRef(op.Ref)
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	action := ActionReplaceRelationship
+	switch op.Op {
+	case "add":
+		action = ActionAddToManyRelationship
+	case "remove":
+		action = ActionDeleteToManyRelationship
+	}
+
+	res, err := o.resourceFor(op.Ref.Type, "/ref/type", action)
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	var (
+		relation jsonapi.Reference
+		found    bool
+	)
+	for _, reference := range res.references() {
+		if reference.Name == op.Ref.Relationship {
+			relation, found = reference, true
+			break
+		}
+	}
+	if !found {
+		return operationResult{}, newOperationError(http.StatusNotFound, fmt.Sprintf("There is no relation with the name %s", op.Ref.Relationship), "/ref/relationship")
+	}
+
+	updater, isRelationshipUpdater := relationshipUpdater(res.source)
+	source, ok := sourceAs[ResourceUpdater](res.source)
+	if !ok && !isRelationshipUpdater {
+		return operationResult{}, newOperationError(http.StatusForbidden, fmt.Sprintf("Resource %s does not support updates", res.name), "/ref/type")
+	}
+
+	var linkage interface{}
+	if err := json.Unmarshal(op.Data, &linkage); err != nil {
+		return operationResult{}, newOperationError(http.StatusBadRequest, err.Error(), "/data")
+	}
+	if err := o.resolveLinkage(linkage); err != nil {
+		return operationResult{}, err
+	}
+	if err := checkLinkageTypes(linkage, relation); err != nil {
+		return operationResult{}, err
+	}
+
+	if err := o.begin(res); err != nil {
+		return operationResult{}, err
+	}
+
+	if isRelationshipUpdater {
+		return operationResult{}, o.updateRelationship(updater, op, id, linkage)
+	}
+
+	response, err := source.FindOne(id, buildRequest(o.c, o.r))
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	if response.Result() == nil {
+		return operationResult{}, newOperationError(http.StatusNotFound, fmt.Sprintf("Resource %s with id %s does not exist", res.name, id), "/ref/id")
+	}
+
+	resType := reflect.TypeOf(response.Result()).Kind()
+	var editObj interface{}
+	if resType == reflect.Struct {
+		editObj = getPointerToStruct(response.Result())
+	} else {
+		editObj = response.Result()
+	}
+
+	if op.Op == "update" {
+		err = processRelationshipsData(linkage, op.Ref.Relationship, editObj)
+	} else {
+		var references []jsonapi.ReferenceID
+		if references, err = toManyReferences(linkage); err == nil {
+			err = editToManyReferences(editObj, relation, op.Op == "add", references)
+		}
+	}
+	if err != nil {
+		if _, ok := err.(HTTPError); ok {
+			return operationResult{}, err
+		}
+		return operationResult{}, newOperationError(http.StatusBadRequest, err.Error(), "/data")
+	}
+
+	if resType == reflect.Struct {
+		_, err = source.Update(reflect.ValueOf(editObj).Elem().Interface(), buildRequest(o.c, o.r))
+	} else {
+		_, err = source.Update(editObj, buildRequest(o.c, o.r))
+	}
+
+	return operationResult{}, err
+}
+
+// updateRelationship passes a relationship operation to the
+// RelationshipUpdater or PolymorphicRelationshipUpdater of the source
+func (o *operationsRun) updateRelationship(updater PolymorphicRelationshipUpdater, op operation, id string, linkage interface{}) error {
+	var (
+		references []jsonapi.ReferenceID
+		err        error
+	)
+	if op.Op == "update" {
+		references, _, err = relationshipReferences(linkage, op.Ref.Relationship)
+	} else {
+		references, err = toManyReferences(linkage)
+	}
+	if err != nil {
+		return err
+	}
+
+	req := buildRequest(o.c, o.r)
+	switch op.Op {
+	case "add":
+		_, err = updater.AddToManyRelationshipReferences(id, op.Ref.Relationship, references, req)
+	case "remove":
+		_, err = updater.DeleteToManyRelationshipReferences(id, op.Ref.Relationship, references, req)
+	default:
+		_, err = updater.ReplaceRelationshipReferences(id, op.Ref.Relationship, references, req)
+	}
+
+	return err
+}
+
+// operationData is the primary data of an add or update operation with all
+// local identifiers replaced by the ids of the created resources.
+type operationData struct {
+	Type string
+	ID   string
+	Lid  string
+	raw  map[string]interface{}
+}
+
+func (o *operationsRun) resolveData(payload json.RawMessage) (operationData, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(payload, &raw); err != nil || raw == nil {
+		return operationData{}, newOperationError(http.StatusBadRequest, "Invalid object. Need a \"data\" object", "/data")
+	}
+
+	data := operationData{raw: raw}
+	data.Type, _ = raw["type"].(string)
+	data.ID, _ = raw["id"].(string)
+	data.Lid, _ = raw["lid"].(string)
+	delete(raw, "lid")
+
+	if data.ID == "" && data.Lid != "" {
+		if id, ok := o.lids[lidKey(data.Type, data.Lid)]; ok {
+			data.ID = id
+			raw["id"] = id
+		}
+	}
+
+	if relationships, ok := raw["relationships"].(map[string]interface{}); ok {
+		for _, relationship := range relationships {
+			if casted, ok := relationship.(map[string]interface{}); ok {
+				if err := o.resolveLinkage(casted["data"]); err != nil {
+					return operationData{}, err
+				}
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// resolveLinkage replaces local identifiers inside of resource linkage
+func (o *operationsRun) resolveLinkage(linkage interface{}) error {
+	switch casted := linkage.(type) {
+	case []interface{}:
+		for _, entry := range casted {
+			if err := o.resolveLinkage(entry); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		lid, ok := casted["lid"].(string)
+		if !ok {
+			return nil
+		}
+
+		resourceType, _ := casted["type"].(string)
+		id, ok := o.lids[lidKey(resourceType, lid)]
+		if !ok {
+			return newOperationError(http.StatusBadRequest, fmt.Sprintf("Unknown local identifier \"%s\" for type %s", lid, resourceType), "/data")
+		}
+
+		casted["id"] = id
+		delete(casted, "lid")
+	}
+
+	return nil
+}
+
+func (o *operationsRun) resolveRef(ref *operationRef) (string, error) {
+	if ref.ID != "" {
+		return ref.ID, nil
+	}
+
+	if ref.Lid != "" {
+		if id, ok := o.lids[lidKey(ref.Type, ref.Lid)]; ok {
+			return id, nil
+		}
+		return "", newOperationError(http.StatusBadRequest, fmt.Sprintf("Unknown local identifier \"%s\" for type %s", ref.Lid, ref.Type), "/ref/lid")
+	}
+
+	return "", newOperationError(http.StatusBadRequest, "\"ref\" needs an \"id\" or \"lid\"", "/ref")
+}
+
+// resourceFor returns the resource of the given type and runs its middlewares
+// for the action, as if the operation was sent to the route of the action.
+func (o *operationsRun) resourceFor(name, pointer string, action Action) (*resource, error) {
+	res := o.api.findResource(name)
+	if res == nil {
+		return nil, newOperationError(http.StatusNotFound, fmt.Sprintf("No resource handler is registered for type %s", name), pointer)
+	}
+
+	if !res.middlewareChain(action, o.c, o.w, o.r) {
+		return nil, errResponseWritten
+	}
+
+	return res, nil
+}
+
+func (o *operationsRun) result(response Responder) (operationResult, error) {
+	document, err := jsonapi.MarshalToStructWithOptions(response.Result(), marshalOptions(o.info))
+	if err != nil {
+		return operationResult{}, err
+	}
+
+	result := operationResult{Meta: response.Metadata()}
+	if document.Data != nil {
+		result.Data = document.Data.DataObject
+	}
+
+	return result, nil
+}
+
+// begin starts the transaction of res before its first operation. Requests
+// with multiple operations are rejected for resources without transactions,
+// because their changes could not be rolled back.
+func (o *operationsRun) begin(res *resource) error {
+	transactional, ok := sourceAs[TransactionalResource](res.source)
+	if !ok {
+		if o.single {
+			return nil
+		}
+		return newOperationError(http.StatusBadRequest, fmt.Sprintf("Resource %s does not support transactions and can only be changed by a single operation", res.name), "")
+	}
+
+	for _, begun := range o.begun {
+		if begun.name == res.name {
+			return nil
+		}
+	}
+
+	if err := transactional.Begin(buildRequest(o.c, o.r)); err != nil {
+		return err
+	}
+	o.begun = append(o.begun, res)
+
+	return nil
+}
+
+func (o *operationsRun) commit() error {
+	for i, res := range o.begun {
+		transactional, _ := sourceAs[TransactionalResource](res.source)
+		if err := transactional.Commit(buildRequest(o.c, o.r)); err != nil {
+			// the failed and all following transactions are still open
+			o.begun = o.begun[i:]
+			if rollbackErr := o.rollback(); rollbackErr != nil {
+				return newRollbackError(errors.Join(err, rollbackErr))
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rollback rolls back all started transactions in reverse order and returns
+// the errors of all failed rollbacks
+func (o *operationsRun) rollback() error {
+	var errs []error
+	for i := len(o.begun) - 1; i >= 0; i-- {
+		transactional, _ := sourceAs[TransactionalResource](o.begun[i].source)
+		if err := transactional.Rollback(buildRequest(o.c, o.r)); err != nil {
+			errs = append(errs, fmt.Errorf("rollback of resource %s failed: %w", o.begun[i].name, err))
+		}
+	}
+	o.begun = nil
+
+	return errors.Join(errs...)
+}
+
+// newRollbackError is returned if operations failed and could not be rolled
+// back completely, so some of their changes may have been applied
+func newRollbackError(err error) HTTPError {
+	return NewHTTPError(err, "Atomic operations failed and could not be rolled back", http.StatusInternalServerError)
+}
+
+func lidKey(resourceType, lid string) string {
+	return resourceType + "/" + lid
+}
+
+func newOperationError(status int, title, pointer string) HTTPError {
+	httpError := newSourceError(nil, status, title, pointer)
+	httpError.Errors[0].Code = codeInvalidOperation
+
+	return httpError
+}
+
+// pointOperationError prefixes all error pointers with the index of the failed operation
+func pointOperationError(httpError HTTPError, index int) error {
+	prefix := fmt.Sprintf("/atomic:operations/%d", index)
+
+	if len(httpError.Errors) == 0 {
+		httpError.Errors = []jsonapi.Error{{
+			Title:  httpError.msg,
+			Status: strconv.Itoa(httpError.status),
+		}}
+	}
+
+	errs := make([]jsonapi.Error, len(httpError.Errors))
+	for i, e := range httpError.Errors {
+		source := jsonapi.ErrorSource{Pointer: prefix}
+		if e.Source != nil {
+			source = *e.Source
+			if source.Parameter == "" {
+				source.Pointer = prefix + source.Pointer
+			}
+		}
+		e.Source = &source
+		errs[i] = e
+	}
+	httpError.Errors = errs
+
+	return httpError
+}
@@ -0,0 +1,155 @@
+package api2go
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type transactionalSource struct {
+	fixtureSource
+	calls       []string
+	commitErr   error
+	rollbackErr error
+}
+
+func (s *transactionalSource) Begin(req Request) error {
+	s.calls = append(s.calls, "begin")
+	return nil
+}
+
+func (s *transactionalSource) Commit(req Request) error {
+	s.calls = append(s.calls, "commit")
+	return s.commitErr
+}
+
+func (s *transactionalSource) Rollback(req Request) error {
+	s.calls = append(s.calls, "rollback")
+	return s.rollbackErr
+}
+
+var _ = Describe("Atomic operations", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *transactionalSource
+	)
+
+	BeforeEach(func() {
+		source = &transactionalSource{fixtureSource: fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+			"2": {ID: "2", Title: "I am NR. 2"},
+		}, true}}
+
+		api = NewAPI("v1")
+		api.AddResource(&Post{}, source)
+		api.AddResource(&Comment{}, &commentSource{true})
+		api.EnableAtomicOperations()
+		rec = httptest.NewRecorder()
+	})
+
+	doRequest := func(payload string) {
+		req, err := http.NewRequest("POST", "/v1/operations", strings.NewReader(payload))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("runs all operations and resolves local identifiers", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "add", "data": {"type": "posts", "lid": "new-post", "attributes": {"title": "Atomic"}}},
+			{"op": "update", "ref": {"type": "posts", "lid": "new-post", "relationship": "comments"}, "data": [{"type": "comments", "id": "7"}]},
+			{"op": "update", "data": {"type": "posts", "id": "1", "attributes": {"title": "Changed"}}},
+			{"op": "remove", "ref": {"type": "posts", "id": "2"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Header().Get("Content-Type")).To(Equal(`application/vnd.api+json;ext="https://jsonapi.org/ext/atomic"`))
+
+		var result struct {
+			Results []map[string]interface{} `json:"atomic:results"`
+		}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &result)).To(Succeed())
+		Expect(result.Results).To(HaveLen(4))
+		Expect(result.Results[0]["data"]).To(HaveKeyWithValue("id", "3"))
+		Expect(result.Results[1]).To(BeEmpty())
+
+		Expect(source.posts).To(HaveLen(2))
+		Expect(source.posts["3"].Title).To(Equal("Atomic"))
+		Expect(source.posts["3"].Comments).To(Equal([]Comment{{ID: "7"}}))
+		Expect(source.posts["1"].Title).To(Equal("Changed"))
+		Expect(source.calls).To(Equal([]string{"begin", "commit"}))
+	})
+
+	It("responds with 204 if no operation returns data", func() {
+		doRequest(`{"atomic:operations": [{"op": "remove", "ref": {"type": "posts", "id": "2"}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.posts).To(HaveLen(1))
+	})
+
+	It("rolls back and points to the failed operation", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "add", "data": {"type": "posts", "attributes": {"title": "Atomic"}}},
+			{"op": "add", "data": {"type": "unicorns", "attributes": {}}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(source.calls).To(Equal([]string{"begin", "rollback"}))
+
+		var httpError HTTPError
+		Expect(json.Unmarshal(rec.Body.Bytes(), &httpError)).To(Succeed())
+		Expect(httpError.Errors).To(HaveLen(1))
+		Expect(httpError.Errors[0].Source.Pointer).To(Equal("/atomic:operations/1/data/type"))
+	})
+
+	It("rolls back the transaction that failed to commit", func() {
+		source.commitErr = NewHTTPError(nil, "Conflict", http.StatusConflict)
+		doRequest(`{"atomic:operations": [{"op": "add", "data": {"type": "posts", "attributes": {"title": "Atomic"}}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(source.calls).To(Equal([]string{"begin", "commit", "rollback"}))
+	})
+
+	It("reports operations that could not be rolled back", func() {
+		source.rollbackErr = errors.New("connection lost")
+		doRequest(`{"atomic:operations": [
+			{"op": "add", "data": {"type": "posts", "attributes": {"title": "Atomic"}}},
+			{"op": "add", "data": {"type": "unicorns", "attributes": {}}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(source.calls).To(Equal([]string{"begin", "rollback"}))
+		Expect(rec.Body.String()).To(ContainSubstring("Atomic operations failed and could not be rolled back"))
+	})
+
+	It("rejects multiple operations on resources without transactions", func() {
+		doRequest(`{"atomic:operations": [
+			{"op": "add", "data": {"type": "posts", "attributes": {"title": "Atomic"}}},
+			{"op": "remove", "ref": {"type": "comments", "id": "1"}}
+		]}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(source.calls).To(Equal([]string{"begin", "rollback"}))
+
+		var httpError HTTPError
+		Expect(json.Unmarshal(rec.Body.Bytes(), &httpError)).To(Succeed())
+		Expect(httpError.Errors).To(HaveLen(1))
+		Expect(httpError.Errors[0].Title).To(Equal("Resource comments does not support transactions and can only be changed by a single operation"))
+		Expect(httpError.Errors[0].Source.Pointer).To(Equal("/atomic:operations/1"))
+
+		rec = httptest.NewRecorder()
+		doRequest(`{"atomic:operations": [{"op": "remove", "ref": {"type": "comments", "id": "1"}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+	})
+
+	It("rejects unknown local identifiers", func() {
+		doRequest(`{"atomic:operations": [{"op": "remove", "ref": {"type": "posts", "lid": "missing"}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/atomic:operations/0/ref/lid"`))
+	})
+
+	It("rejects unknown operations", func() {
+		doRequest(`{"atomic:operations": [{"op": "upsert", "data": {"type": "posts"}}]}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/atomic:operations/0/op"`))
+	})
+})
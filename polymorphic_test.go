@@ -0,0 +1,245 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type referenceUpdaterSource struct {
+	*ticketSource
+	calls map[string][]jsonapi.ReferenceID
+}
+
+func (s *referenceUpdaterSource) ReplaceRelationshipReferences(ID, name string, references []jsonapi.ReferenceID, req Request) (Responder, error) {
+	s.calls["replace"] = references
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *referenceUpdaterSource) AddToManyRelationshipReferences(ID, name string, references []jsonapi.ReferenceID, req Request) (Responder, error) {
+	s.calls["add"] = references
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *referenceUpdaterSource) DeleteToManyRelationshipReferences(ID, name string, references []jsonapi.ReferenceID, req Request) (Responder, error) {
+	s.calls["delete"] = references
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+type relatedGroupSource struct {
+	*ticketSource
+	findOneCalls int
+}
+
+func (s *relatedGroupSource) FindOne(ID string, req Request) (Responder, error) {
+	s.findOneCalls++
+	return s.ticketSource.FindOne(ID, req)
+}
+
+func (s *relatedGroupSource) FindRelated(parentType, parentID, relation string, req Request) (Responder, error) {
+	return s.FindAll(req)
+}
+
+var _ = Describe("Polymorphic relationships", func() {
+	var (
+		api        *API
+		activities *ticketSource
+		rec        *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		users := &jsonapi.Schema{Type: "users", Attributes: []jsonapi.AttributeDefinition{{Name: "name"}}}
+		groups := &jsonapi.Schema{Type: "groups", Attributes: []jsonapi.AttributeDefinition{{Name: "name"}}}
+		schema := &jsonapi.Schema{
+			Type: "activities",
+			Relationships: []jsonapi.Reference{
+				{Types: []string{"users", "groups"}, Name: "subject", Relationship: jsonapi.ToOneRelationship},
+				{Types: []string{"users", "groups"}, Name: "members", Relationship: jsonapi.ToManyRelationship},
+			},
+		}
+
+		user := users.NewResource("1")
+		user.Attributes["name"] = "Marvin"
+		group := groups.NewResource("1")
+		group.Attributes["name"] = "Admins"
+
+		activity := schema.NewResource("1")
+		activity.Relationships["subject"] = jsonapi.RelationshipDataContainer{DataObject: &jsonapi.RelationshipData{Type: "groups", ID: "1"}}
+		activity.Relationships["members"] = jsonapi.RelationshipDataContainer{DataArray: []jsonapi.RelationshipData{{Type: "users", ID: "1"}}}
+
+		activities = &ticketSource{tickets: map[string]*jsonapi.Resource{"1": activity}}
+		api = NewAPI("v1")
+		api.AddSchemaResource(schema, activities)
+		api.AddSchemaResource(users, &ticketSource{tickets: map[string]*jsonapi.Resource{"1": user}})
+		api.AddSchemaResource(groups, &ticketSource{tickets: map[string]*jsonapi.Resource{"1": group}})
+	})
+
+	request := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		rec = httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("returns the related resources of every type", func() {
+		request("GET", "/v1/activities/1/subject", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"data": {
+			"type": "groups",
+			"id": "1",
+			"attributes": {"name": "Admins"}
+		}}`))
+
+		request("POST", "/v1/activities/1/relationships/members", `{"data": [{"type": "groups", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+		request("GET", "/v1/activities/1/members", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"data": [
+			{"type": "users", "id": "1", "attributes": {"name": "Marvin"}},
+			{"type": "groups", "id": "1", "attributes": {"name": "Admins"}}
+		]}`))
+	})
+
+	It("skips missing related resources", func() {
+		activity := activities.tickets["1"]
+		activity.Relationships["members"] = jsonapi.RelationshipDataContainer{DataArray: []jsonapi.RelationshipData{
+			{Type: "groups", ID: "2"},
+			{Type: "users", ID: "1"},
+		}}
+		request("GET", "/v1/activities/1/members", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"data": [
+			{"type": "users", "id": "1", "attributes": {"name": "Marvin"}}
+		]}`))
+
+		activity.Relationships["subject"] = jsonapi.RelationshipDataContainer{DataObject: &jsonapi.RelationshipData{Type: "groups", ID: "2"}}
+		request("GET", "/v1/activities/1/subject", "")
+		Expect(rec.Code).To(Equal(http.StatusNotFound))
+		Expect(rec.Body.String()).To(ContainSubstring("Related resource groups with id 2 does not exist"))
+
+		activity.Relationships["subject"] = jsonapi.RelationshipDataContainer{}
+		request("GET", "/v1/activities/1/subject", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"data": null}`))
+	})
+
+	It("loads related resources with FindRelated and paginates them", func() {
+		groups := &jsonapi.Schema{Type: "groups", Attributes: []jsonapi.AttributeDefinition{{Name: "name"}}}
+		admins, guests := groups.NewResource("1"), groups.NewResource("2")
+		admins.Attributes["name"], guests.Attributes["name"] = "Admins", "Guests"
+		source := &relatedGroupSource{ticketSource: &ticketSource{tickets: map[string]*jsonapi.Resource{"1": admins, "2": guests}}}
+
+		api = NewAPI("v1")
+		api.AddSchemaResource(activities.tickets["1"].Schema, activities)
+		api.AddSchemaResource(groups, source)
+		activities.tickets["1"].Relationships["members"] = jsonapi.RelationshipDataContainer{DataArray: []jsonapi.RelationshipData{
+			{Type: "groups", ID: "2"},
+			{Type: "groups", ID: "1"},
+		}}
+
+		request("GET", "/v1/activities/1/members", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"data": [
+			{"type": "groups", "id": "2", "attributes": {"name": "Guests"}},
+			{"type": "groups", "id": "1", "attributes": {"name": "Admins"}}
+		]}`))
+		Expect(source.findOneCalls).To(BeZero())
+
+		request("GET", "/v1/activities/1/members?page[offset]=1&page[limit]=1", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{
+			"links": {
+				"first": "/v1/activities/1/members?page[limit]=1&page[offset]=0",
+				"prev": "/v1/activities/1/members?page[limit]=1&page[offset]=0"
+			},
+			"data": [{"type": "groups", "id": "1", "attributes": {"name": "Admins"}}]
+		}`))
+	})
+
+	It("includes resources of every type", func() {
+		request("GET", "/v1/activities/1?include=subject,members", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var document map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+		Expect(document["included"]).To(ConsistOf(
+			HaveKeyWithValue("type", "groups"),
+			HaveKeyWithValue("type", "users"),
+		))
+	})
+
+	It("keeps the type of every entry of the linkage", func() {
+		request("PATCH", "/v1/activities/1/relationships/subject", `{"data": {"type": "users", "id": "1"}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(activities.tickets["1"].Relationships["subject"].DataObject).To(Equal(&jsonapi.RelationshipData{Type: "users", ID: "1"}))
+
+		request("DELETE", "/v1/activities/1/relationships/members", `{"data": [{"type": "groups", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(activities.tickets["1"].Relationships["members"].DataArray).To(Equal([]jsonapi.RelationshipData{{Type: "users", ID: "1"}}))
+	})
+
+	It("rejects types that the relationship can not reference", func() {
+		request("PATCH", "/v1/activities/1/relationships/subject", `{"data": {"type": "posts", "id": "1"}}`)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/type"`))
+
+		request("POST", "/v1/activities/1/relationships/members", `{"data": [{"type": "users", "id": "2"}, {"type": "posts", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/1/type"`))
+	})
+
+	It("passes the type of every entry to a PolymorphicRelationshipUpdater", func() {
+		updater := &referenceUpdaterSource{ticketSource: activities, calls: map[string][]jsonapi.ReferenceID{}}
+		api = NewAPI("v1")
+		api.AddSchemaResource(activities.tickets["1"].Schema, updater)
+		api.EnableAtomicOperations()
+
+		request("PATCH", "/v1/activities/1/relationships/subject", `{"data": {"type": "users", "id": "1"}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(updater.calls["replace"]).To(Equal([]jsonapi.ReferenceID{{ID: "1", Type: "users", Name: "subject", Relationship: jsonapi.ToOneRelationship}}))
+
+		request("POST", "/v1/activities/1/relationships/members", `{"data": [{"type": "groups", "id": "1"}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(updater.calls["add"]).To(Equal([]jsonapi.ReferenceID{{ID: "1", Type: "groups", Relationship: jsonapi.ToManyRelationship}}))
+
+		request("POST", "/v1/operations", `{"atomic:operations": [{
+			"op": "remove",
+			"ref": {"type": "activities", "id": "1", "relationship": "members"},
+			"data": [{"type": "users", "id": "1"}]
+		}]}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(updater.calls["delete"]).To(Equal([]jsonapi.ReferenceID{{ID: "1", Type: "users", Relationship: jsonapi.ToManyRelationship}}))
+
+		request("POST", "/v1/operations", `{"atomic:operations": [{
+			"op": "add",
+			"ref": {"type": "activities", "id": "1", "relationship": "members"},
+			"data": [{"type": "users", "id": "1"}, {"type": "posts", "id": "1"}]
+		}]}`)
+		Expect(rec.Code).To(Equal(http.StatusConflict))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/atomic:operations/0/data/1/type"`))
+	})
+
+	It("documents the types of the related resources", func() {
+		document := map[string]interface{}{}
+		marshalled, err := json.Marshal(api.OpenAPI(OpenAPIInfo{Title: "Activities", Version: "1.0.0"}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(marshalled, &document)).To(Succeed())
+
+		related := document["paths"].(map[string]interface{})["/v1/activities/{id}/members"].(map[string]interface{})
+		content := related["get"].(map[string]interface{})["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"]
+		data := content.(map[string]interface{})[defaultContentTypHeader].(map[string]interface{})["schema"].(map[string]interface{})["properties"].(map[string]interface{})["data"]
+		Expect(data).To(Equal(map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{"oneOf": []interface{}{
+				map[string]interface{}{"$ref": "#/components/schemas/usersResource"},
+				map[string]interface{}{"$ref": "#/components/schemas/groupsResource"},
+			}},
+		}))
+	})
+})
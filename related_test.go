@@ -0,0 +1,106 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// relatedUserSource finds the authors of posts by the id of the post
+type relatedUserSource struct {
+	authors map[string]User
+	calls   [][]string
+}
+
+func (s *relatedUserSource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{}, nil
+}
+
+func (s *relatedUserSource) FindRelated(parentType, parentID, relation string, req Request) (Responder, error) {
+	s.calls = append(s.calls, []string{parentType, parentID, relation})
+	if user, ok := s.authors[parentID]; ok {
+		return &Response{Res: user}, nil
+	}
+	return &Response{}, nil
+}
+
+type userSliceSource struct{}
+
+func (s *userSliceSource) FindAll(req Request) (Responder, error) {
+	if req.QueryParams["postsID"][0] == "1" {
+		return &Response{Res: []User{{ID: "1", Name: "Dieter"}}}, nil
+	}
+	return &Response{Res: []User{}}, nil
+}
+
+func (s *userSliceSource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{}, nil
+}
+
+var _ = Describe("Related resources", func() {
+	var (
+		api *API
+		rec *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		api = NewAPI("v1")
+		api.AddResource(Post{}, &fixtureSource{map[string]*Post{"1": {ID: "1"}}, false})
+		rec = httptest.NewRecorder()
+	})
+
+	get := func(url string) {
+		req, err := http.NewRequest("GET", url, nil)
+		Expect(err).ToNot(HaveOccurred())
+		rec = httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	Context("with a RelatedResourceFinder", func() {
+		var source *relatedUserSource
+
+		BeforeEach(func() {
+			source = &relatedUserSource{authors: map[string]User{"1": {ID: "1", Name: "Dieter"}}}
+			api.AddResource(User{}, source)
+		})
+
+		It("returns the related resource of to-one relationships", func() {
+			get("/v1/posts/1/author")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`{"data": {
+				"type": "users",
+				"id": "1",
+				"attributes": {"name": "Dieter", "info": ""}
+			}}`))
+			Expect(source.calls).To(Equal([][]string{{"posts", "1", "author"}}))
+		})
+
+		It("returns null for empty to-one relationships", func() {
+			get("/v1/posts/2/author")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`{"data": null}`))
+		})
+	})
+
+	Context("with FindAll", func() {
+		BeforeEach(func() {
+			api.AddResource(User{}, &userSliceSource{})
+		})
+
+		It("returns a single resource or null for to-one relationships", func() {
+			get("/v1/posts/1/author")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`{"data": {
+				"type": "users",
+				"id": "1",
+				"attributes": {"name": "Dieter", "info": ""}
+			}}`))
+
+			get("/v1/posts/2/author")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`{"data": null}`))
+		})
+	})
+})
@@ -0,0 +1,209 @@
+package api2go
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type statusSource struct {
+	*fixtureSource
+	code int
+	err  error
+}
+
+func (s statusSource) Update(obj interface{}, req Request) (Responder, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	if _, err := s.fixtureSource.Update(obj, req); err != nil {
+		return nil, err
+	}
+	return &Response{Code: s.code}, nil
+}
+
+type relationshipCall struct {
+	method string
+	id     string
+	name   string
+	ids    []string
+}
+
+type relationshipSource struct {
+	*fixtureSource
+	calls []relationshipCall
+	code  int
+}
+
+func (s *relationshipSource) ReplaceRelationship(ID, name string, IDs []string, req Request) (Responder, error) {
+	s.calls = append(s.calls, relationshipCall{"replace", ID, name, IDs})
+	return &Response{Code: s.code}, nil
+}
+
+func (s *relationshipSource) AddToManyRelationship(ID, name string, IDs []string, req Request) (Responder, error) {
+	s.calls = append(s.calls, relationshipCall{"add", ID, name, IDs})
+	return &Response{Code: s.code}, nil
+}
+
+func (s *relationshipSource) DeleteToManyRelationship(ID, name string, IDs []string, req Request) (Responder, error) {
+	s.calls = append(s.calls, relationshipCall{"delete", ID, name, IDs})
+	return &Response{Res: Post{ID: ID, Title: "Relationships"}, Code: s.code}, nil
+}
+
+type lockedPost struct {
+	Post
+}
+
+func (p *lockedPost) AddToManyIDs(name string, IDs []string) error {
+	return NewHTTPError(nil, "comments are locked", http.StatusForbidden)
+}
+
+type lockedPostSource struct {
+	updated bool
+}
+
+func (s *lockedPostSource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: lockedPost{Post{ID: ID}}}, nil
+}
+
+func (s *lockedPostSource) Update(obj interface{}, req Request) (Responder, error) {
+	s.updated = true
+	return &Response{}, nil
+}
+
+var _ = Describe("Relationship updates", func() {
+	var (
+		api   *API
+		posts map[string]*Post
+		rec   *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		posts = map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!", Author: &User{ID: "1"}},
+		}
+		api = NewAPI("v1")
+		rec = httptest.NewRecorder()
+	})
+
+	request := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		rec = httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	Context("with Update", func() {
+		var source *statusSource
+
+		BeforeEach(func() {
+			source = &statusSource{fixtureSource: &fixtureSource{posts, false}}
+			api.AddResource(Post{}, source)
+		})
+
+		It("returns the updated linkage for 200 OK", func() {
+			source.code = http.StatusOK
+			request("PATCH", "/v1/posts/1/relationships/author", `{"data": {"type": "users", "id": "2"}}`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(MatchJSON(`{
+				"links": {
+					"self": "/v1/posts/1/relationships/author",
+					"related": "/v1/posts/1/author"
+				},
+				"data": {"type": "users", "id": "2"}
+			}`))
+		})
+
+		It("returns nothing for 202 Accepted", func() {
+			source.code = http.StatusAccepted
+			request("POST", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "1"}]}`)
+			Expect(rec.Code).To(Equal(http.StatusAccepted))
+			Expect(rec.Body.String()).To(BeEmpty())
+			Expect(posts["1"].Comments).To(Equal([]Comment{{ID: "1"}}))
+		})
+
+		It("treats a missing status code as 204 No Content", func() {
+			request("PATCH", "/v1/posts/1/relationships/author", `{"data": null}`)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(posts["1"].Author).To(BeNil())
+		})
+
+		It("rejects other status codes", func() {
+			source.code = http.StatusCreated
+			request("PATCH", "/v1/posts/1/relationships/author", `{"data": null}`)
+			Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		})
+
+		It("returns errors of the resource without saving it", func() {
+			locked := &lockedPostSource{}
+			api.AddResource(lockedPost{}, locked)
+			request("POST", "/v1/lockedPosts/1/relationships/comments", `{"data": [{"type": "comments", "id": "1"}]}`)
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+			Expect(rec.Body.String()).To(ContainSubstring(`"title":"comments are locked"`))
+			Expect(locked.updated).To(BeFalse())
+		})
+
+		It("returns errors of Update", func() {
+			source.err = NewHTTPError(nil, "locked", http.StatusLocked)
+			request("PATCH", "/v1/posts/1/relationships/author", `{"data": null}`)
+			Expect(rec.Code).To(Equal(http.StatusLocked))
+			Expect(rec.Body.String()).To(ContainSubstring(`"title":"locked"`))
+		})
+	})
+
+	Context("with a RelationshipUpdater", func() {
+		var source *relationshipSource
+
+		BeforeEach(func() {
+			source = &relationshipSource{fixtureSource: &fixtureSource{posts, false}}
+			api.AddResource(Post{}, source)
+			api.EnableAtomicOperations()
+		})
+
+		It("replaces relationships", func() {
+			request("PATCH", "/v1/posts/1/relationships/author", `{"data": {"type": "users", "id": "2"}}`)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+			request("PATCH", "/v1/posts/1/relationships/author", `{"data": null}`)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+			request("PATCH", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "1"}, {"type": "comments", "id": "2"}]}`)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+			Expect(source.calls).To(Equal([]relationshipCall{
+				{"replace", "1", "author", []string{"2"}},
+				{"replace", "1", "author", []string{}},
+				{"replace", "1", "comments", []string{"1", "2"}},
+			}))
+			Expect(posts["1"].Author).To(Equal(&User{ID: "1"}))
+		})
+
+		It("adds and deletes members of to-many relationships", func() {
+			request("POST", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "3"}]}`)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+
+			source.code = http.StatusOK
+			request("DELETE", "/v1/posts/1/relationships/comments", `{"data": [{"type": "comments", "id": "3"}]}`)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+			Expect(rec.Body.String()).To(ContainSubstring(`"data":[]`))
+
+			Expect(source.calls).To(Equal([]relationshipCall{
+				{"add", "1", "comments", []string{"3"}},
+				{"delete", "1", "comments", []string{"3"}},
+			}))
+		})
+
+		It("is used by atomic operations", func() {
+			request("POST", "/v1/operations", `{"atomic:operations": [{
+				"op": "add",
+				"ref": {"type": "posts", "id": "1", "relationship": "comments"},
+				"data": [{"type": "comments", "id": "4"}]
+			}]}`)
+			Expect(rec.Code).To(Equal(http.StatusNoContent))
+			Expect(source.calls).To(Equal([]relationshipCall{{"add", "1", "comments", []string{"4"}}}))
+		})
+	})
+})
@@ -9,4 +9,19 @@ type Request struct {
 	Pagination   map[string]string
 	Header       http.Header
 	Context      APIContexter
+	// Include contains the relationship paths of the include query parameter,
+	// e.g. ["author", "comments.author"]. It is nil if no include was requested.
+	Include []string
+	// Sort contains the parsed sort query parameter in the requested order
+	Sort []SortField
+	// Filters contains all parsed filter[...] query parameters
+	Filters []Filter
+	// Fields contains the requested fields of each type of the fields[type]
+	// query parameters, e.g. {"posts": {"title"}}, so sources can load only
+	// these. Types without requested fields are missing.
+	Fields map[string][]string
+	// Extensions and Profiles contain the negotiated JSON:API extension and
+	// profile URIs the client asked for
+	Extensions []string
+	Profiles   []string
 }
@@ -17,6 +17,8 @@ type Response struct {
 	Code       int
 	Meta       map[string]interface{}
 	Pagination Pagination
+	// Profiles contains the URIs of the JSON:API profiles applied to the response
+	Profiles []string
 }
 
 // Metadata returns additional meta data
@@ -34,6 +36,11 @@ func (r Response) StatusCode() int {
 	return r.Code
 }
 
+// AppliedProfiles returns the profiles applied to the response
+func (r Response) AppliedProfiles() []string {
+	return r.Profiles
+}
+
 func buildLink(base string, r *http.Request, pagination map[string]string) jsonapi.Link {
 	params := r.URL.Query()
 	for k, v := range pagination {
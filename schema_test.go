@@ -0,0 +1,153 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type ticketSource struct {
+	tickets map[string]*jsonapi.Resource
+}
+
+func (s *ticketSource) FindAll(req Request) (Responder, error) {
+	tickets := []*jsonapi.Resource{}
+	for _, ticket := range s.tickets {
+		tickets = append(tickets, ticket)
+	}
+	return &Response{Res: tickets}, nil
+}
+
+func (s *ticketSource) FindOne(ID string, req Request) (Responder, error) {
+	ticket, ok := s.tickets[ID]
+	if !ok {
+		return nil, NewHTTPError(nil, "ticket not found", http.StatusNotFound)
+	}
+	return &Response{Res: ticket}, nil
+}
+
+func (s *ticketSource) Create(obj interface{}, req Request) (Responder, error) {
+	ticket := obj.(*jsonapi.Resource)
+	ticket.ID = "2"
+	s.tickets[ticket.ID] = ticket
+	return &Response{Res: ticket, Code: http.StatusCreated}, nil
+}
+
+func (s *ticketSource) Update(obj interface{}, req Request) (Responder, error) {
+	ticket := obj.(*jsonapi.Resource)
+	s.tickets[ticket.ID] = ticket
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *ticketSource) Delete(ID string, req Request) (Responder, error) {
+	delete(s.tickets, ID)
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+var _ = Describe("Schema resources", func() {
+	var (
+		api    *API
+		source *ticketSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		schema := &jsonapi.Schema{
+			Type: "tickets",
+			Attributes: []jsonapi.AttributeDefinition{
+				{Name: "title", Type: jsonapi.StringAttribute, Required: true},
+				{Name: "priority", Type: jsonapi.NumberAttribute},
+			},
+			Relationships: []jsonapi.Reference{
+				{Type: "users", Name: "assignee", Relationship: jsonapi.ToOneRelationship},
+			},
+		}
+
+		ticket := schema.NewResource("1")
+		ticket.Attributes["title"] = "Broken"
+		ticket.Relationships["assignee"] = jsonapi.RelationshipDataContainer{DataObject: &jsonapi.RelationshipData{Type: "users", ID: "1"}}
+
+		source = &ticketSource{tickets: map[string]*jsonapi.Resource{"1": ticket}}
+		api = NewAPI("v1")
+		api.AddSchemaResource(schema, source)
+		rec = httptest.NewRecorder()
+	})
+
+	request := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		rec = httptest.NewRecorder()
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	It("returns resources of the schema", func() {
+		request("GET", "/v1/tickets/1", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"data": {
+			"type": "tickets",
+			"id": "1",
+			"attributes": {"title": "Broken", "priority": null},
+			"relationships": {"assignee": {
+				"links": {
+					"self": "/v1/tickets/1/relationships/assignee",
+					"related": "/v1/tickets/1/assignee"
+				},
+				"data": {"type": "users", "id": "1"}
+			}}
+		}}`))
+
+		request("GET", "/v1/tickets/1/relationships/assignee", "")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(ContainSubstring(`"data":{"type":"users","id":"1"}`))
+	})
+
+	It("creates resources of the schema", func() {
+		request("POST", "/v1/tickets", `{"data": {"type": "tickets", "attributes": {"title": "New", "priority": 1}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(source.tickets["2"].Attributes).To(Equal(map[string]interface{}{"title": "New", "priority": 1.0}))
+		Expect(source.tickets["2"].Schema).ToNot(BeNil())
+	})
+
+	It("validates resources against the schema", func() {
+		request("POST", "/v1/tickets", `{"data": {"type": "tickets", "attributes": {"priority": 1}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/attributes/title"`))
+
+		request("POST", "/v1/tickets", `{"data": {"type": "tickets", "attributes": {"title": "New", "state": "open"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).To(ContainSubstring(`"pointer":"/data/attributes/state"`))
+	})
+
+	It("updates resources of the schema", func() {
+		request("PATCH", "/v1/tickets/1", `{"data": {"type": "tickets", "id": "1", "attributes": {"priority": 3}}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.tickets["1"].Attributes).To(Equal(map[string]interface{}{"title": "Broken", "priority": 3.0}))
+
+		request("PATCH", "/v1/tickets/1/relationships/assignee", `{"data": {"type": "users", "id": "2"}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.tickets["1"].Relationships["assignee"].DataObject).To(Equal(&jsonapi.RelationshipData{Type: "users", ID: "2"}))
+	})
+
+	It("documents the attributes of the schema", func() {
+		document := map[string]interface{}{}
+		marshalled, err := json.Marshal(api.OpenAPI(OpenAPIInfo{Title: "Tickets", Version: "1.0.0"}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(marshalled, &document)).To(Succeed())
+
+		schemas := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+		properties := schemas["ticketsResource"].(map[string]interface{})["properties"].(map[string]interface{})
+		Expect(properties["attributes"]).To(Equal(map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"title":    map[string]interface{}{"type": "string"},
+				"priority": map[string]interface{}{"type": "number"},
+			},
+			"required": []interface{}{"title"},
+		}))
+	})
+})
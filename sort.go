@@ -0,0 +1,81 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+const codeInvalidSort = "API2GO_INVALID_SORT_QUERY_PARAM"
+
+// SortField is one entry of the sort query parameter. `sort=-name,age` results
+// in SortField{Field: "name", Descending: true} and SortField{Field: "age"}.
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// parseSortFields parses the sort query parameters in the given order,
+// repeated parameters like `sort=name&sort=-age` are combined
+func parseSortFields(r *http.Request) []SortField {
+	values, ok := r.URL.Query()["sort"]
+	if !ok {
+		return nil
+	}
+
+	result := []SortField{}
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			descending := strings.HasPrefix(field, "-")
+			field = strings.TrimPrefix(field, "-")
+			if field == "" {
+				continue
+			}
+
+			result = append(result, SortField{Field: field, Descending: descending})
+		}
+	}
+
+	return result
+}
+
+// validateSort returns a 400 HTTPError if the source implements
+// SortableResource and the request contains sort fields it does not allow.
+func validateSort(source interface{}, r *http.Request) error {
+	sortable, ok := sourceAs[SortableResource](source)
+	if !ok {
+		return nil
+	}
+
+	allowed := map[string]bool{}
+	for _, field := range sortable.SortableFields() {
+		allowed[field] = true
+	}
+
+	httpError := NewHTTPError(nil, "Some requested sort fields were invalid", http.StatusBadRequest)
+	for _, field := range parseSortFields(r) {
+		if allowed[field.Field] {
+			continue
+		}
+
+		httpError.Errors = append(httpError.Errors, jsonapi.Error{
+			Status: strconv.Itoa(http.StatusBadRequest),
+			Code:   codeInvalidSort,
+			Title:  fmt.Sprintf(`Sorting by "%s" is not supported`, field.Field),
+			Detail: "Please make sure you do only sort by supported fields",
+			Source: &jsonapi.ErrorSource{
+				Parameter: "sort",
+			},
+		})
+	}
+
+	if len(httpError.Errors) > 0 {
+		return httpError
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type sortableSource struct {
+	fixtureSource
+	sort []SortField
+}
+
+func (s *sortableSource) SortableFields() []string {
+	return []string{"title", "value"}
+}
+
+func (s *sortableSource) FindAll(req Request) (Responder, error) {
+	s.sort = req.Sort
+	return s.fixtureSource.FindAll(req)
+}
+
+var _ = Describe("Sorting", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *sortableSource
+	)
+
+	BeforeEach(func() {
+		source = &sortableSource{fixtureSource: fixtureSource{map[string]*Post{
+			"1": {ID: "1", Title: "Hello, World!"},
+		}, false}}
+
+		api = NewAPI("v1")
+		api.AddResource(Post{}, source)
+		rec = httptest.NewRecorder()
+	})
+
+	It("parses the sort parameter", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?sort=-title,value", nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buildRequest(&APIContext{}, req).Sort).To(Equal([]SortField{
+			{Field: "title", Descending: true},
+			{Field: "value"},
+		}))
+	})
+
+	It("passes allowed sort fields to the source", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?sort=value,-title", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.sort).To(Equal([]SortField{
+			{Field: "value"},
+			{Field: "title", Descending: true},
+		}))
+	})
+
+	It("rejects unsupported sort fields before calling the source", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?sort=-title,author.name", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(source.sort).To(BeNil())
+
+		var httpError HTTPError
+		Expect(json.Unmarshal(rec.Body.Bytes(), &httpError)).To(Succeed())
+		Expect(httpError.Errors).To(Equal([]jsonapi.Error{{
+			Status: "400",
+			Code:   codeInvalidSort,
+			Title:  `Sorting by "author.name" is not supported`,
+			Detail: "Please make sure you do only sort by supported fields",
+			Source: &jsonapi.ErrorSource{Parameter: "sort"},
+		}}))
+	})
+	It("combines repeated sort parameters", func() {
+		req, err := http.NewRequest("GET", "/v1/posts?sort=value&sort=-title", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(source.sort).To(Equal([]SortField{
+			{Field: "value"},
+			{Field: "title", Descending: true},
+		}))
+
+		rec = httptest.NewRecorder()
+		req, err = http.NewRequest("GET", "/v1/posts?sort=value&sort=author.name", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+})
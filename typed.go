@@ -0,0 +1,177 @@
+package api2go
+
+import (
+	"reflect"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+// TypedResponse is the generic counterpart of Response. It is returned by the
+// typed source interfaces, so the result has the resource type instead of
+// interface{}.
+type TypedResponse[T any] struct {
+	Res        T
+	Code       int
+	Meta       map[string]interface{}
+	Pagination Pagination
+}
+
+// Metadata returns additional meta data
+func (r TypedResponse[T]) Metadata() map[string]interface{} {
+	return r.Meta
+}
+
+// Result returns the actual payload
+func (r TypedResponse[T]) Result() interface{} {
+	return r.Res
+}
+
+// StatusCode sets the return status code
+func (r TypedResponse[T]) StatusCode() int {
+	return r.Code
+}
+
+// untyped converts the response into a Response, a nil pointer result becomes
+// a nil interface so the api can check for a missing result.
+func (r TypedResponse[T]) untyped() Response {
+	response := Response{Res: r.Res, Code: r.Code, Meta: r.Meta, Pagination: r.Pagination}
+	if value := reflect.ValueOf(&r.Res).Elem(); value.Kind() == reflect.Ptr && value.IsNil() {
+		response.Res = nil
+	}
+
+	return response
+}
+
+// The TypedResourceGetter interface is the generic counterpart of ResourceGetter
+type TypedResourceGetter[T jsonapi.MarshalIdentifier] interface {
+	// FindOne returns an object by its ID
+	// Possible Responder success status code 200
+	FindOne(ID string, req Request) (TypedResponse[T], error)
+}
+
+// The TypedFindAll interface is the generic counterpart of FindAll
+type TypedFindAll[T jsonapi.MarshalIdentifier] interface {
+	// FindAll returns all objects
+	FindAll(req Request) (TypedResponse[[]T], error)
+}
+
+// The TypedPaginatedFindAll interface is the generic counterpart of PaginatedFindAll
+type TypedPaginatedFindAll[T jsonapi.MarshalIdentifier] interface {
+	PaginatedFindAll(req Request) (totalCount uint, response TypedResponse[[]T], err error)
+}
+
+// The TypedResourceCreator interface is the generic counterpart of ResourceCreator.
+// The same status codes as for ResourceCreator are possible.
+type TypedResourceCreator[T jsonapi.MarshalIdentifier] interface {
+	Create(obj T, req Request) (TypedResponse[T], error)
+}
+
+// The TypedResourceUpdater interface is the generic counterpart of ResourceUpdater.
+// The same status codes as for ResourceUpdater are possible.
+type TypedResourceUpdater[T jsonapi.MarshalIdentifier] interface {
+	TypedResourceGetter[T]
+	Update(obj T, req Request) (TypedResponse[T], error)
+}
+
+// The TypedCRUD interface embeds all typed interfaces at once. Delete does not
+// depend on the resource type, so the untyped ResourceDeleter is used.
+type TypedCRUD[T jsonapi.MarshalIdentifier] interface {
+	TypedResourceCreator[T]
+	ResourceDeleter
+	TypedResourceUpdater[T]
+}
+
+// AddTypedResource registers a source that implements the typed interfaces
+// for the resource type T, e.g. TypedCRUD[Post]. T can either be a struct or
+// a struct pointer, just like the prototype of AddResource. All optional
+// interfaces that do not depend on T, like SortableResource, are used as well.
+func AddTypedResource[T jsonapi.MarshalIdentifier](api *API, source TypedResourceGetter[T]) *Resource {
+	var prototype T
+	if resourceType := reflect.TypeOf(&prototype).Elem(); resourceType.Kind() == reflect.Ptr {
+		prototype = reflect.New(resourceType.Elem()).Interface().(T)
+	}
+
+	adapters := []interface{}{typedGetter[T]{source}}
+	if casted, ok := source.(TypedFindAll[T]); ok {
+		adapters = append(adapters, typedFindAll[T]{casted})
+	}
+	if casted, ok := source.(TypedPaginatedFindAll[T]); ok {
+		adapters = append(adapters, typedPaginatedFindAll[T]{casted})
+	}
+	if casted, ok := source.(TypedResourceCreator[T]); ok {
+		adapters = append(adapters, typedCreator[T]{casted})
+	}
+	if casted, ok := source.(TypedResourceUpdater[T]); ok {
+		adapters = append(adapters, typedUpdater[T]{typedGetter[T]{casted}, casted})
+	}
+
+	return &Resource{api.addResource(prototype, typedSource{adapters: append(adapters, source)})}
+}
+
+// typedSource is registered as the source of typed resources. It contains
+// adapters from the typed to the untyped interfaces and the typed source
+// itself for all other optional interfaces.
+type typedSource struct {
+	adapters []interface{}
+}
+
+// sourceAs casts a source to one of the source interfaces. For typed sources
+// the interface is looked up in their adapters.
+func sourceAs[I any](source interface{}) (I, bool) {
+	if typed, ok := source.(typedSource); ok {
+		for _, adapter := range typed.adapters {
+			if casted, ok := adapter.(I); ok {
+				return casted, true
+			}
+		}
+	}
+
+	casted, ok := source.(I)
+	return casted, ok
+}
+
+type typedGetter[T jsonapi.MarshalIdentifier] struct {
+	source TypedResourceGetter[T]
+}
+
+func (s typedGetter[T]) FindOne(ID string, req Request) (Responder, error) {
+	response, err := s.source.FindOne(ID, req)
+	return response.untyped(), err
+}
+
+type typedFindAll[T jsonapi.MarshalIdentifier] struct {
+	source TypedFindAll[T]
+}
+
+func (s typedFindAll[T]) FindAll(req Request) (Responder, error) {
+	response, err := s.source.FindAll(req)
+	return response.untyped(), err
+}
+
+type typedPaginatedFindAll[T jsonapi.MarshalIdentifier] struct {
+	source TypedPaginatedFindAll[T]
+}
+
+func (s typedPaginatedFindAll[T]) PaginatedFindAll(req Request) (uint, Responder, error) {
+	count, response, err := s.source.PaginatedFindAll(req)
+	return count, response.untyped(), err
+}
+
+type typedCreator[T jsonapi.MarshalIdentifier] struct {
+	source TypedResourceCreator[T]
+}
+
+func (s typedCreator[T]) Create(obj interface{}, req Request) (Responder, error) {
+	response, err := s.source.Create(obj.(T), req)
+	return response.untyped(), err
+}
+
+type typedUpdater[T jsonapi.MarshalIdentifier] struct {
+	typedGetter[T]
+	updater TypedResourceUpdater[T]
+}
+
+func (s typedUpdater[T]) Update(obj interface{}, req Request) (Responder, error) {
+	response, err := s.updater.Update(obj.(T), req)
+	return response.untyped(), err
+}
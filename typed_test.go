@@ -0,0 +1,169 @@
+package api2go
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type Task struct {
+	ID    string `json:"-"`
+	Title string `json:"title"`
+	Done  bool   `json:"done"`
+}
+
+func (t Task) GetID() string {
+	return t.ID
+}
+
+func (t *Task) SetID(ID string) error {
+	t.ID = ID
+	return nil
+}
+
+type typedTaskSource struct {
+	tasks map[string]Task
+}
+
+func (s *typedTaskSource) FindAll(req Request) (TypedResponse[[]Task], error) {
+	result := []Task{}
+	for _, task := range s.tasks {
+		result = append(result, task)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return TypedResponse[[]Task]{Res: result}, nil
+}
+
+func (s *typedTaskSource) FindOne(ID string, req Request) (TypedResponse[Task], error) {
+	task, ok := s.tasks[ID]
+	if !ok {
+		return TypedResponse[Task]{}, NewHTTPError(nil, "task not found", http.StatusNotFound)
+	}
+	return TypedResponse[Task]{Res: task}, nil
+}
+
+func (s *typedTaskSource) Create(task Task, req Request) (TypedResponse[Task], error) {
+	task.ID = strconv.Itoa(len(s.tasks) + 1)
+	s.tasks[task.ID] = task
+	return TypedResponse[Task]{Res: task, Code: http.StatusCreated}, nil
+}
+
+func (s *typedTaskSource) Update(task Task, req Request) (TypedResponse[Task], error) {
+	s.tasks[task.ID] = task
+	return TypedResponse[Task]{Code: http.StatusNoContent}, nil
+}
+
+func (s *typedTaskSource) Delete(ID string, req Request) (Responder, error) {
+	delete(s.tasks, ID)
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+func (s *typedTaskSource) SortableFields() []string {
+	return []string{"title"}
+}
+
+type typedReadOnlyTaskSource struct {
+	tasks map[string]*Task
+}
+
+func (s typedReadOnlyTaskSource) FindOne(ID string, req Request) (TypedResponse[*Task], error) {
+	return TypedResponse[*Task]{Res: s.tasks[ID]}, nil
+}
+
+var _ = Describe("Typed resources", func() {
+	var (
+		api    *API
+		rec    *httptest.ResponseRecorder
+		source *typedTaskSource
+	)
+
+	BeforeEach(func() {
+		source = &typedTaskSource{tasks: map[string]Task{
+			"1": {ID: "1", Title: "Write tests"},
+		}}
+		api = NewAPI("v1")
+		AddTypedResource[Task](api, source)
+		rec = httptest.NewRecorder()
+	})
+
+	It("finds all and one resource", func() {
+		req, err := http.NewRequest("GET", "/v1/tasks", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(MatchJSON(`{"data": [{
+			"type": "tasks",
+			"id": "1",
+			"attributes": {"title": "Write tests", "done": false}
+		}]}`))
+
+		rec = httptest.NewRecorder()
+		req, err = http.NewRequest("GET", "/v1/tasks/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+
+	It("creates a resource with the typed Create", func() {
+		req, err := http.NewRequest("POST", "/v1/tasks", strings.NewReader(`{"data": {"type": "tasks", "attributes": {"title": "Ship it"}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(rec.Header().Get("Location")).To(Equal("/v1/tasks/2"))
+		Expect(source.tasks["2"]).To(Equal(Task{ID: "2", Title: "Ship it"}))
+	})
+
+	It("updates and deletes a resource", func() {
+		req, err := http.NewRequest("PATCH", "/v1/tasks/1", strings.NewReader(`{"data": {"type": "tasks", "id": "1", "attributes": {"done": true}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.tasks["1"].Done).To(BeTrue())
+
+		rec = httptest.NewRecorder()
+		req, err = http.NewRequest("DELETE", "/v1/tasks/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.tasks).To(BeEmpty())
+	})
+
+	It("uses optional interfaces of the typed source", func() {
+		req, err := http.NewRequest("GET", "/v1/tasks?sort=done", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("only generates routes for the implemented interfaces", func() {
+		api = NewAPI("v1")
+		AddTypedResource[*Task](api, typedReadOnlyTaskSource{tasks: map[string]*Task{"1": {ID: "1", Title: "Read me"}}})
+
+		req, err := http.NewRequest("GET", "/v1/tasks/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var document map[string]interface{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &document)).To(Succeed())
+		Expect(document["data"]).To(HaveKeyWithValue("id", "1"))
+
+		rec = httptest.NewRecorder()
+		req, err = http.NewRequest("POST", "/v1/tasks", strings.NewReader(`{"data": {"type": "tasks", "attributes": {}}}`))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+
+		rec = httptest.NewRecorder()
+		req, err = http.NewRequest("OPTIONS", "/v1/tasks/1", nil)
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+		Expect(rec.Header().Get("Allow")).To(Equal("OPTIONS,GET"))
+	})
+})
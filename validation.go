@@ -0,0 +1,388 @@
+package api2go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/jtumidanski/api2go/jsonapi"
+)
+
+const codeInvalidAttribute = "API2GO_INVALID_ATTRIBUTE"
+
+// The Validator interface can be implemented by resources to validate
+// themselves after a create or update request was unmarshalled. If Validate
+// returns ValidationErrors, the request fails with 422 Unprocessable Entity
+// and one error object per attribute. All other errors are returned as is.
+type Validator interface {
+	Validate() error
+}
+
+// ValidationError is the validation error of a single attribute
+type ValidationError struct {
+	// Attribute is the json name of the attribute, e.g. "email"
+	Attribute string
+	// Detail describes the problem, e.g. "must not be empty"
+	Detail string
+}
+
+// ValidationErrors contains all validation errors of a resource
+type ValidationErrors []ValidationError
+
+// Error returns all validation errors as one string
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, validationError := range e {
+		messages[i] = validationError.Attribute + " " + validationError.Detail
+	}
+
+	return strings.Join(messages, ", ")
+}
+
+// httpError converts the validation errors into a 422 Unprocessable Entity
+// with source pointers to the invalid attributes
+func (e ValidationErrors) httpError() HTTPError {
+	httpError := NewHTTPError(e, "Unprocessable Entity", http.StatusUnprocessableEntity)
+	for _, validationError := range e {
+		httpError.Errors = append(httpError.Errors, jsonapi.Error{
+			Status: strconv.Itoa(http.StatusUnprocessableEntity),
+			Code:   codeInvalidAttribute,
+			Title:  "Invalid attribute",
+			Detail: validationError.Attribute + " " + validationError.Detail,
+			Source: &jsonapi.ErrorSource{Pointer: "/data/attributes/" + validationError.Attribute},
+		})
+	}
+
+	return httpError
+}
+
+// validate runs the struct tag validation and the Validator of obj
+func validate(obj interface{}) error {
+	errs := ValidateStruct(obj)
+	errs = append(errs, validateSchema(obj)...)
+
+	validator, ok := obj.(Validator)
+	if !ok && reflect.ValueOf(obj).Kind() == reflect.Struct {
+		validator, ok = getPointerToStruct(obj).(Validator)
+	}
+
+	if ok {
+		err := validator.Validate()
+		var validationErrors ValidationErrors
+		if errors.As(err, &validationErrors) {
+			errs = append(errs, validationErrors...)
+		} else if err != nil {
+			return err
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs.httpError()
+	}
+
+	return nil
+}
+
+// validateSchema checks the required attributes of jsonapi.Resource values
+// with a schema
+func validateSchema(obj interface{}) ValidationErrors {
+	var resource jsonapi.Resource
+	switch casted := obj.(type) {
+	case *jsonapi.Resource:
+		resource = *casted
+	case jsonapi.Resource:
+		resource = casted
+	default:
+		return nil
+	}
+
+	if resource.Schema == nil {
+		return nil
+	}
+
+	var errs ValidationErrors
+	for _, attribute := range resource.Schema.Attributes {
+		if attribute.Required && resource.Attributes[attribute.Name] == nil {
+			errs = append(errs, ValidationError{Attribute: attribute.Name, Detail: "is required"})
+		}
+	}
+
+	return errs
+}
+
+// ValidateStruct validates the attributes of a resource struct with their
+// `validate` struct tags. Rules are separated by commas:
+//
+//	required      the attribute must not be the zero value
+//	min=N, max=N  the minimum and maximum length of strings, slices and maps
+//	              or the minimum and maximum of numbers
+//	email         the attribute must be an email address
+//	oneof=a b c   the attribute must be one of the space separated values
+//
+// email and oneof accept empty values, combine them with required if needed.
+// AddResource rejects structs with invalid rules, see CheckValidationRules.
+// ValidateStruct does not validate structs with invalid rules.
+func ValidateStruct(obj interface{}) ValidationErrors {
+	value := reflect.ValueOf(obj)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return validateFields(value)
+}
+
+// CheckValidationRules returns an error if the `validate` struct tags of obj
+// or its embedded structs contain unknown rules or invalid arguments
+func CheckValidationRules(obj interface{}) error {
+	return checkValidationRules(reflect.TypeOf(obj))
+}
+
+func checkValidationRules(structType reflect.Type) error {
+	for structType != nil && structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if _, err := structRules(structType); err != nil {
+		return err
+	}
+
+	for i := 0; i < structType.NumField(); i++ {
+		if _, embedded, ok := validatedField(structType.Field(i)); ok && embedded {
+			if err := checkValidationRules(structType.Field(i).Type); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validationRule is a parsed rule of a `validate` struct tag
+type validationRule struct {
+	name     string
+	argument string
+	limit    float64
+}
+
+// parsedRules caches the rules of every struct field by struct type
+var parsedRules sync.Map
+
+// structRules returns the parsed rules of every field of a struct type,
+// indexed like its fields
+func structRules(structType reflect.Type) ([][]validationRule, error) {
+	if cached, ok := parsedRules.Load(structType); ok {
+		return cached.([][]validationRule), nil
+	}
+
+	rules := make([][]validationRule, structType.NumField())
+	for i := range rules {
+		field := structType.Field(i)
+		tag := field.Tag.Get("validate")
+		if _, embedded, ok := validatedField(field); !ok || embedded || tag == "" {
+			continue
+		}
+
+		fieldRules, err := parseRules(field.Type, tag)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validate tag of field %s.%s: %w", structType.Name(), field.Name, err)
+		}
+		rules[i] = fieldRules
+	}
+
+	parsedRules.Store(structType, rules)
+	return rules, nil
+}
+
+// parseRules parses the rules of a field of the given type
+func parseRules(fieldType reflect.Type, tag string) ([]validationRule, error) {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+
+	var rules []validationRule
+	for _, rule := range strings.Split(tag, ",") {
+		name, argument, _ := strings.Cut(rule, "=")
+		parsed := validationRule{name: name, argument: argument}
+		switch name {
+		case "required", "oneof":
+		case "min", "max":
+			limit, err := strconv.ParseFloat(argument, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid argument %q of validation rule %s", argument, name)
+			}
+			if !measurable(fieldType.Kind()) {
+				return nil, fmt.Errorf("validation rule %s is not supported for %s", name, fieldType)
+			}
+			parsed.limit = limit
+		case "email":
+			if fieldType.Kind() != reflect.String {
+				return nil, fmt.Errorf("validation rule email is not supported for %s", fieldType)
+			}
+		default:
+			return nil, fmt.Errorf("unknown validation rule %s", name)
+		}
+		rules = append(rules, parsed)
+	}
+
+	return rules, nil
+}
+
+// validatedField returns the attribute name of a struct field and whether it
+// is an embedded struct whose fields are validated instead, ok is false for
+// fields that are not attributes
+func validatedField(field reflect.StructField) (string, bool, bool) {
+	jsonTag := field.Tag.Get("json")
+	if !field.IsExported() || jsonTag == "-" {
+		return "", false, false
+	}
+
+	name := strings.Split(jsonTag, ",")[0]
+	if field.Anonymous && name == "" {
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if fieldType.Kind() == reflect.Struct {
+			return "", true, true
+		}
+	}
+
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, false, true
+}
+
+func validateFields(value reflect.Value) ValidationErrors {
+	rules, err := structRules(value.Type())
+	if err != nil {
+		return nil
+	}
+
+	var errs ValidationErrors
+	structType := value.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		name, embedded, ok := validatedField(structType.Field(i))
+		if !ok {
+			continue
+		}
+
+		if embedded {
+			embeddedValue := value.Field(i)
+			if embeddedValue.Kind() == reflect.Ptr {
+				if embeddedValue.IsNil() {
+					continue
+				}
+				embeddedValue = embeddedValue.Elem()
+			}
+			errs = append(errs, validateFields(embeddedValue)...)
+			continue
+		}
+
+		if detail := validateField(value.Field(i), rules[i]); detail != "" {
+			errs = append(errs, ValidationError{Attribute: name, Detail: detail})
+		}
+	}
+
+	return errs
+}
+
+// validateField returns the detail of the first violated rule or an empty
+// string if the value is valid
+func validateField(value reflect.Value, rules []validationRule) string {
+	if value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			for _, rule := range rules {
+				if rule.name == "required" {
+					return "is required"
+				}
+			}
+			return ""
+		}
+		value = value.Elem()
+	}
+
+	for _, rule := range rules {
+		switch rule.name {
+		case "required":
+			if value.IsZero() {
+				return "is required"
+			}
+		case "min", "max":
+			size, unit := measure(value)
+			if rule.name == "min" && size < rule.limit {
+				return "must be at least " + rule.argument + unit
+			}
+			if rule.name == "max" && size > rule.limit {
+				return "must be at most " + rule.argument + unit
+			}
+		case "email":
+			address := value.String()
+			if parsed, err := mail.ParseAddress(address); address != "" && (err != nil || parsed.Address != address) {
+				return "must be a valid email address"
+			}
+		case "oneof":
+			allowed := strings.Fields(rule.argument)
+			actual := fmt.Sprint(value.Interface())
+			found := false
+			for _, candidate := range allowed {
+				found = found || candidate == actual
+			}
+			if !found && !value.IsZero() {
+				return "must be one of " + strings.Join(allowed, ", ")
+			}
+		}
+	}
+
+	return ""
+}
+
+// measurable reports whether min and max can be used for values of the kind
+func measurable(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// measure returns the length of strings, slices and maps or the value of
+// numbers, together with the unit used in error details
+func measure(value reflect.Value) (float64, string) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), " characters long"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), " elements long"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), ""
+	}
+
+	return 0, ""
+}
@@ -0,0 +1,217 @@
+package api2go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type Profile struct {
+	Bio string `json:"bio" validate:"max=10"`
+}
+
+type Account struct {
+	ID       string   `json:"-"`
+	Email    string   `json:"email" validate:"required,email"`
+	Name     string   `json:"name" validate:"required,min=2,max=20"`
+	Role     string   `json:"role,omitempty" validate:"oneof=admin user"`
+	Age      *int     `json:"age" validate:"min=18"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Password string   `json:"-" validate:"required"`
+	Profile
+}
+
+func (a Account) GetID() string {
+	return a.ID
+}
+
+func (a *Account) SetID(ID string) error {
+	a.ID = ID
+	return nil
+}
+
+func (a Account) Validate() error {
+	if a.Name == "root" {
+		return ValidationErrors{{Attribute: "name", Detail: "is reserved"}}
+	}
+	if a.Name == "error" {
+		return NewHTTPError(nil, "validation failed", http.StatusInternalServerError)
+	}
+	return nil
+}
+
+type accountSource struct {
+	accounts map[string]Account
+}
+
+func (s *accountSource) FindOne(ID string, req Request) (Responder, error) {
+	return &Response{Res: s.accounts[ID]}, nil
+}
+
+func (s *accountSource) Create(obj interface{}, req Request) (Responder, error) {
+	account := obj.(Account)
+	account.ID = "2"
+	s.accounts[account.ID] = account
+	return &Response{Res: account, Code: http.StatusCreated}, nil
+}
+
+func (s *accountSource) Update(obj interface{}, req Request) (Responder, error) {
+	account := obj.(Account)
+	s.accounts[account.ID] = account
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+type wrappedValidation struct{}
+
+func (w wrappedValidation) Validate() error {
+	return fmt.Errorf("checking name: %w", ValidationErrors{{Attribute: "name", Detail: "is reserved"}})
+}
+
+var _ = Describe("Validation", func() {
+	var (
+		api    *API
+		source *accountSource
+		rec    *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		source = &accountSource{accounts: map[string]Account{
+			"1": {ID: "1", Email: "jane@example.com", Name: "Jane"},
+		}}
+		api = NewAPI("v1")
+		api.AddResource(Account{}, source)
+		api.EnableAtomicOperations()
+		rec = httptest.NewRecorder()
+	})
+
+	request := func(method, url, body string) {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		api.Handler().ServeHTTP(rec, req)
+	}
+
+	Context("ValidateStruct", func() {
+		It("validates all tagged attributes", func() {
+			age := 17
+			errs := ValidateStruct(&Account{
+				Email:   "jane",
+				Name:    "J",
+				Role:    "owner",
+				Age:     &age,
+				Tags:    []string{"a", "b", "c"},
+				Profile: Profile{Bio: "far too long"},
+			})
+			Expect(errs).To(Equal(ValidationErrors{
+				{Attribute: "email", Detail: "must be a valid email address"},
+				{Attribute: "name", Detail: "must be at least 2 characters long"},
+				{Attribute: "role", Detail: "must be one of admin, user"},
+				{Attribute: "age", Detail: "must be at least 18"},
+				{Attribute: "tags", Detail: "must be at most 2 elements long"},
+				{Attribute: "bio", Detail: "must be at most 10 characters long"},
+			}))
+		})
+
+		It("accepts valid structs", func() {
+			Expect(ValidateStruct(Account{Email: "jane@example.com", Name: "Jane", Role: "admin"})).To(BeEmpty())
+		})
+
+		It("rejects unknown rules and invalid arguments when the resource is added", func() {
+			type InvalidRules struct {
+				Name string `json:"name" validate:"required,omitempty"`
+			}
+			Expect(CheckValidationRules(InvalidRules{})).To(MatchError("invalid validate tag of field InvalidRules.Name: unknown validation rule omitempty"))
+			Expect(func() { ValidateStruct(InvalidRules{}) }).ToNot(Panic())
+
+			type invalidArgument struct {
+				Account
+				Count int `json:"count" validate:"max=ten"`
+			}
+			Expect(CheckValidationRules(&invalidArgument{})).To(MatchError(ContainSubstring(`invalid argument "ten" of validation rule max`)))
+
+			type invalidEmbedded struct {
+				Post
+				InvalidRules
+			}
+			Expect(func() { NewAPI("v1").AddResource(invalidEmbedded{}, source) }).To(PanicWith(ContainSubstring("unknown validation rule omitempty")))
+		})
+	})
+
+	It("rejects invalid resources with 422 and source pointers", func() {
+		request("POST", "/v1/accounts", `{"data": {"type": "accounts", "attributes": {"email": "jane"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rec.Body.String()).To(MatchJSON(`{"errors": [
+			{
+				"status": "422",
+				"code": "API2GO_INVALID_ATTRIBUTE",
+				"title": "Invalid attribute",
+				"detail": "email must be a valid email address",
+				"source": {"pointer": "/data/attributes/email"}
+			},
+			{
+				"status": "422",
+				"code": "API2GO_INVALID_ATTRIBUTE",
+				"title": "Invalid attribute",
+				"detail": "name is required",
+				"source": {"pointer": "/data/attributes/name"}
+			}
+		]}`))
+		Expect(source.accounts).ToNot(HaveKey("2"))
+	})
+
+	It("creates valid resources", func() {
+		request("POST", "/v1/accounts", `{"data": {"type": "accounts", "attributes": {"email": "joe@example.com", "name": "Joe"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusCreated))
+		Expect(source.accounts).To(HaveKey("2"))
+	})
+
+	It("validates the merged resource on updates", func() {
+		request("PATCH", "/v1/accounts/1", `{"data": {"type": "accounts", "id": "1", "attributes": {"role": "admin"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusNoContent))
+		Expect(source.accounts["1"].Role).To(Equal("admin"))
+
+		rec = httptest.NewRecorder()
+		request("PATCH", "/v1/accounts/1", `{"data": {"type": "accounts", "id": "1", "attributes": {"name": ""}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(source.accounts["1"].Name).To(Equal("Jane"))
+	})
+
+	It("calls the Validator of the resource", func() {
+		request("POST", "/v1/accounts", `{"data": {"type": "accounts", "attributes": {"email": "root@example.com", "name": "root"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rec.Body.String()).To(ContainSubstring(`"detail":"name is reserved"`))
+
+		rec = httptest.NewRecorder()
+		request("POST", "/v1/accounts", `{"data": {"type": "accounts", "attributes": {"email": "error@example.com", "name": "error"}}}`)
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rec.Body.String()).To(ContainSubstring(`"title":"validation failed"`))
+	})
+
+	It("validates resources of atomic operations", func() {
+		request("POST", "/v1/operations", `{"atomic:operations": [{
+			"op": "add",
+			"data": {"type": "accounts", "attributes": {"email": "joe@example.com"}}
+		}]}`)
+		Expect(rec.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rec.Body.String()).To(ContainSubstring(`"source":{"pointer":"/atomic:operations/0/data/attributes/name"}`))
+	})
+
+	It("maps wrapped validation errors to 422", func() {
+		err := validate(wrappedValidation{})
+		var httpError HTTPError
+		Expect(errors.As(err, &httpError)).To(BeTrue())
+		Expect(httpError.status).To(Equal(http.StatusUnprocessableEntity))
+		Expect(httpError.Errors[0].Source.Pointer).To(Equal("/data/attributes/name"))
+	})
+
+	It("can be inspected with errors.As", func() {
+		err := validate(Account{Email: "jane@example.com"})
+		var validationErrors ValidationErrors
+		Expect(errors.As(err, &validationErrors)).To(BeTrue())
+		Expect(validationErrors).To(Equal(ValidationErrors{{Attribute: "name", Detail: "is required"}}))
+	})
+})